@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,29 +14,128 @@ import (
 	"ikurotime/code-engine/config"
 	"ikurotime/code-engine/internal/handlers"
 	"ikurotime/code-engine/internal/services"
+	"ikurotime/code-engine/internal/store"
 )
 
 func main() {
 	logger := log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
 
-	config, err := config.LoadConfig()
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		logger.Fatalf("Failed to load config: %v", err)
 	}
 
+	languages, err := config.LoadLanguages(cfg.LanguagesFile)
+	if err != nil {
+		logger.Fatalf("Failed to load languages: %v", err)
+	}
+
+	// The database keeps the problem catalog and the submission history
+	var db store.Store
+	var submissions services.SubmissionRecorder
+	if cfg.Database.Driver == "sqlite" || cfg.Database.Host != "" {
+		database, err := openDatabase(cfg.Database)
+		if err != nil {
+			logger.Fatalf("Failed to open database: %v", err)
+		}
+		defer database.Close()
+		db, submissions = database, database
+	}
+
+	var artifacts *services.ArtifactCache
+	if cfg.ArtifactCache.Dir != "" {
+		artifacts, err = services.NewArtifactCache(cfg.ArtifactCache.Dir, int64(cfg.ArtifactCache.MaxSize)<<20, logger)
+		if err != nil {
+			logger.Fatalf("Failed to open artifact cache: %v", err)
+		}
+	}
+
 	// Initialize services
-	executor := services.NewExecutor(config.Server.MaxConcurrentExecutions, time.Duration(config.Server.ExecutionTimeout)*time.Second, logger)
-	handler := handlers.NewHandler(executor, logger)
+	var runtime services.Runtime
+	switch cfg.Runtime.Backend {
+	case "api":
+		runtime = services.NewDockerAPIRuntime(cfg.Runtime.Socket)
+	default:
+		runtime = services.NewDockerCLIRuntime()
+	}
+	executor := services.NewExecutor(runtime, services.ExecutorConfig{
+		Languages:      languages,
+		MaxConcurrent:  cfg.Server.MaxConcurrentExecutions,
+		Timeout:        time.Duration(cfg.Server.ExecutionTimeout) * time.Second,
+		MaxStdinBytes:  cfg.Server.MaxStdinBytes,
+		MaxOutputBytes: cfg.Server.MaxOutputBytes,
+		MaxBundleBytes: cfg.Server.MaxBundleBytes,
+		AcquireTimeout: time.Duration(cfg.Server.AcquireTimeout) * time.Second,
+		CPUs:           cfg.Container.CPULimit,
+		MemoryMB:       cfg.Container.MemoryLimit,
+		Pool: services.PoolConfig{
+			HealthCheckInterval: time.Duration(cfg.Pool.HealthCheckInterval) * time.Second,
+			MaxAge:              time.Duration(cfg.Pool.MaxContainerAge) * time.Second,
+			MaxExecutions:       cfg.Pool.MaxExecutions,
+		},
+		Instance:  cfg.Pool.Instance,
+		Reconcile: services.ReconcileMode(cfg.Pool.Reconcile),
+		Sessions: services.SessionConfig{
+			IdleTimeout:   time.Duration(cfg.Sessions.IdleTimeout) * time.Second,
+			MaxDuration:   time.Duration(cfg.Sessions.MaxDuration) * time.Second,
+			MaxConcurrent: cfg.Sessions.MaxConcurrent,
+		},
+		Sandbox: services.SandboxConfig{
+			User:               cfg.Sandbox.User,
+			WritableRoot:       cfg.Sandbox.WritableRoot,
+			WorkDirMB:          cfg.Sandbox.WorkDirSize,
+			TmpMB:              cfg.Sandbox.TmpSize,
+			PidsLimit:          cfg.Sandbox.PidsLimit,
+			KeepCapabilities:   cfg.Sandbox.KeepCapabilities,
+			AllowNewPrivileges: cfg.Sandbox.AllowNewPrivileges,
+			MaxFileSizeMB:      cfg.Sandbox.MaxFileSize,
+			MaxOpenFiles:       cfg.Sandbox.MaxOpenFiles,
+			SeccompProfile:     cfg.Sandbox.SeccompProfile,
+		},
+		Submissions: submissions,
+		Artifacts:   artifacts,
+	}, logger)
+	workers := cfg.Jobs.Workers
+	if workers == 0 {
+		workers = cfg.Server.MaxConcurrentExecutions
+	}
+	jobs := services.NewJobQueue(executor, services.JobQueueConfig{
+		Workers:     workers,
+		QueueSize:   cfg.Jobs.QueueSize,
+		Retention:   time.Duration(cfg.Jobs.Retention) * time.Second,
+		MaxFinished: cfg.Jobs.MaxFinished,
+	}, logger)
+
+	handler := handlers.NewHandler(executor, jobs, db, cfg.Server.AdminToken, cfg.Server.MaxRequestBytes, logger)
 
 	// Setup routes
 	router := http.NewServeMux()
 	router.HandleFunc("/", handler.Home)
 	router.HandleFunc("/health", handler.HealthCheck)
 	router.HandleFunc("/execute", handler.Execute)
+	router.HandleFunc("POST /execute/stream", handler.ExecuteStream)
+	router.HandleFunc("POST /judge", handler.Judge)
+	router.HandleFunc("POST /jobs", handler.SubmitJob)
+	router.HandleFunc("GET /jobs/{id}", handler.GetJob)
+	router.HandleFunc("DELETE /jobs/{id}", handler.CancelJob)
+	router.HandleFunc("GET /sessions", handler.Session)
+	if db != nil {
+		router.HandleFunc("GET /problems", handler.ProblemsPage)
+		router.HandleFunc("GET /problems/{id}", handler.ProblemPage)
+		router.HandleFunc("POST /problems/{id}/submissions", handler.SubmitProblem)
+		router.HandleFunc("GET /admin/problems", handler.ListProblems)
+		router.HandleFunc("POST /admin/problems", handler.CreateProblem)
+		router.HandleFunc("GET /admin/problems/{id}", handler.GetProblem)
+		router.HandleFunc("PUT /admin/problems/{id}", handler.UpdateProblem)
+		router.HandleFunc("DELETE /admin/problems/{id}", handler.DeleteProblem)
+		router.HandleFunc("GET /submissions", handler.ListSubmissions)
+	} else {
+		logger.Println("No database configured, problem catalog and submission history disabled")
+	}
 
 	// Create server
 	server := &http.Server{
-		Addr:    config.Server.Port,
+		Addr:    cfg.Server.Port,
 		Handler: router,
 	}
 
@@ -66,9 +167,41 @@ func main() {
 		logger.Println("HTTP server shutdown completed")
 	}
 
+	// Cancel pending jobs before their containers go away
+	logger.Println("Shutting down job queue...")
+	jobs.Shutdown()
+
 	// Shutdown executor and cleanup containers
 	logger.Println("Shutting down executor and cleaning up containers...")
 	executor.Shutdown()
 
 	logger.Println("Graceful shutdown completed")
 }
+
+func openDatabase(cfg config.DatabaseConfig) (*store.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if cfg.Driver == "sqlite" {
+		return store.OpenSQLite(ctx, cfg.Path)
+	}
+	return store.OpenPostgres(ctx, postgresDSN(cfg))
+}
+
+// postgresDSN builds the connection URL of a database.
+func postgresDSN(db config.DatabaseConfig) string {
+	host := db.Host
+	if db.Port != "" {
+		host = net.JoinHostPort(db.Host, db.Port)
+	}
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(db.User, db.Password),
+		Host:   host,
+		Path:   db.Name,
+	}
+	if db.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {db.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
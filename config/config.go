@@ -12,25 +12,110 @@ type ServerConfig struct {
 	Port                    string `yaml:"port"`
 	MaxConcurrentExecutions int    `yaml:"maxConcurrentExecutions"`
 	ExecutionTimeout        int    `yaml:"executionTimeout"`
+	MaxStdinBytes           int    `yaml:"maxStdinBytes" validate:"gte=0"`
+	MaxOutputBytes          int    `yaml:"maxOutputBytes" validate:"gte=0"`
+	MaxRequestBytes         int64  `yaml:"maxRequestBytes" validate:"gte=0"`
+	MaxBundleBytes          int    `yaml:"maxBundleBytes" validate:"gte=0"`
+	AcquireTimeout          int    `yaml:"acquireTimeout" validate:"gte=0"`
+	// AdminToken is the bearer token of the admin API, which is disabled
+	// while it is empty.
+	AdminToken string `yaml:"adminToken"`
 }
 
+// ContainerConfig holds the default sandbox limits; languages may override
+// them in the language registry. MemoryLimit is in megabytes and zero
+// values use the executor's defaults.
 type ContainerConfig struct {
-	CPULimit    float64 `yaml:"cpuLimit"`
-	MemoryLimit int     `yaml:"memoryLimit"`
+	CPULimit    float64 `yaml:"cpuLimit" validate:"omitempty,gt=0,lte=64"`
+	MemoryLimit int     `yaml:"memoryLimit" validate:"omitempty,min=6,max=65536"`
 }
 
+// SandboxConfig is the security profile of every sandbox container. Sizes
+// are in megabytes. Zero values are the hardened defaults: code runs as
+// nobody on a read-only root filesystem without capabilities and with
+// limited processes, file sizes and open files. writableRoot,
+// keepCapabilities, allowNewPrivileges, user root and limits of -1 turn
+// protections off.
+type SandboxConfig struct {
+	User               string `yaml:"user"`
+	WritableRoot       bool   `yaml:"writableRoot"`
+	WorkDirSize        int    `yaml:"workDirSize" validate:"gte=0"`
+	TmpSize            int    `yaml:"tmpSize" validate:"gte=0"`
+	PidsLimit          int    `yaml:"pidsLimit" validate:"gte=-1"`
+	KeepCapabilities   bool   `yaml:"keepCapabilities"`
+	AllowNewPrivileges bool   `yaml:"allowNewPrivileges"`
+	MaxFileSize        int    `yaml:"maxFileSize" validate:"gte=-1"`
+	MaxOpenFiles       int    `yaml:"maxOpenFiles" validate:"gte=-1"`
+	SeccompProfile     string `yaml:"seccompProfile" validate:"omitempty,file"`
+}
+
+// PoolConfig controls the lifetime of pooled sandbox containers. Durations
+// are in seconds; zero maxContainerAge and maxExecutions mean unlimited.
+// Instance labels the containers of this server (default: host name) and
+// reconcile decides what happens to leftovers of a previous run.
+type PoolConfig struct {
+	HealthCheckInterval int    `yaml:"healthCheckInterval" validate:"gte=0"`
+	MaxContainerAge     int    `yaml:"maxContainerAge" validate:"gte=0"`
+	MaxExecutions       int    `yaml:"maxExecutions" validate:"gte=0"`
+	Instance            string `yaml:"instance"`
+	Reconcile           string `yaml:"reconcile" validate:"omitempty,oneof=remove dry-run off"`
+}
+
+// JobsConfig controls the asynchronous job queue. Workers defaults to
+// server.maxConcurrentExecutions and retention is in seconds.
+type JobsConfig struct {
+	Workers     int `yaml:"workers" validate:"gte=0"`
+	QueueSize   int `yaml:"queueSize" validate:"gte=0"`
+	Retention   int `yaml:"retention" validate:"gte=0"`
+	MaxFinished int `yaml:"maxFinished" validate:"gte=0"`
+}
+
+// SessionsConfig limits interactive sessions. Timeouts are in seconds and
+// maxConcurrent defaults to half of server.maxConcurrentExecutions.
+type SessionsConfig struct {
+	IdleTimeout   int `yaml:"idleTimeout" validate:"gte=0"`
+	MaxDuration   int `yaml:"maxDuration" validate:"gte=0"`
+	MaxConcurrent int `yaml:"maxConcurrent" validate:"gte=0"`
+}
+
+// ArtifactCacheConfig locates the cache of compiled code on the host, which
+// is disabled while Dir is empty. MaxSize is in megabytes.
+type ArtifactCacheConfig struct {
+	Dir     string `yaml:"dir"`
+	MaxSize int    `yaml:"maxSize" validate:"required_with=Dir,gte=0"`
+}
+
+type RuntimeConfig struct {
+	Backend string `yaml:"backend" validate:"omitempty,oneof=cli api"`
+	Socket  string `yaml:"socket"`
+}
+
+// DatabaseConfig locates the database of the problem catalog and the
+// submission history: an SQLite file at Path, or a PostgreSQL server, which
+// is the default driver. Without the sqlite driver or a Host there is no
+// database, and both features are disabled.
 type DatabaseConfig struct {
+	Driver   string `yaml:"driver" validate:"omitempty,oneof=postgres sqlite"`
+	Path     string `yaml:"path" validate:"required_if=Driver sqlite"`
 	Name     string `yaml:"name"`
 	Host     string `yaml:"host"`
 	Port     string `yaml:"port"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
+	SSLMode  string `yaml:"sslMode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
 }
 
 type Config struct {
-	Server    ServerConfig    `yaml:"server"`
-	Container ContainerConfig `yaml:"container"`
-	Database  DatabaseConfig  `yaml:"database"`
+	Server        ServerConfig        `yaml:"server"`
+	LanguagesFile string              `yaml:"languagesFile"`
+	Container     ContainerConfig     `yaml:"container"`
+	Sandbox       SandboxConfig       `yaml:"sandbox"`
+	Pool          PoolConfig          `yaml:"pool"`
+	Jobs          JobsConfig          `yaml:"jobs"`
+	Sessions      SessionsConfig      `yaml:"sessions"`
+	Runtime       RuntimeConfig       `yaml:"runtime"`
+	Database      DatabaseConfig      `yaml:"database"`
+	ArtifactCache ArtifactCacheConfig `yaml:"artifactCache"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadConfig runs LoadConfig on content as the configuration of the "test"
+// environment.
+func loadConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "config"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", ".env.test.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("APP_ENV", "test")
+
+	return LoadConfig()
+}
+
+func TestLoadConfigExample(t *testing.T) {
+	example, err := os.ReadFile(".env.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(t, string(example))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Container.CPULimit != 0.5 || cfg.Container.MemoryLimit != 50 {
+		t.Errorf("got container limits %+v, want 0.5 CPUs and 50 MB", cfg.Container)
+	}
+	if cfg.LanguagesFile != "config/languages.yaml" || cfg.Runtime.Backend != "api" {
+		t.Errorf("got languages file %q and backend %q", cfg.LanguagesFile, cfg.Runtime.Backend)
+	}
+}
+
+func TestLoadConfigLeavesLimitsOptional(t *testing.T) {
+	cfg, err := loadConfig(t, "server:\n  port: :8080\n")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Container.CPULimit != 0 || cfg.Container.MemoryLimit != 0 {
+		t.Errorf("got container limits %+v, want them unset", cfg.Container)
+	}
+}
+
+func TestLoadConfigRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"too many cpus", "container:\n  cpuLimit: 128\n", "CPULimit"},
+		{"negative cpus", "container:\n  cpuLimit: -1\n", "CPULimit"},
+		{"too little memory", "container:\n  memoryLimit: 2\n", "MemoryLimit"},
+		{"negative container age", "pool:\n  maxContainerAge: -1\n", "MaxContainerAge"},
+		{"unknown reconcile mode", "pool:\n  reconcile: adopt\n", "Reconcile"},
+		{"unknown backend", "runtime:\n  backend: ssh\n", "Backend"},
+		{"negative stdin limit", "server:\n  maxStdinBytes: -1\n", "MaxStdinBytes"},
+		{"negative output limit", "server:\n  maxOutputBytes: -1\n", "MaxOutputBytes"},
+		{"pids limit below -1", "sandbox:\n  pidsLimit: -2\n", "PidsLimit"},
+		{"unknown ssl mode", "database:\n  sslMode: always\n", "SSLMode"},
+	}
+
+	for _, test := range tests {
+		_, err := loadConfig(t, test.content)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: got error %v, want one mentioning %s", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	t.Setenv("APP_ENV", "missing")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig succeeded without a configuration file")
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/pkg"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const DefaultLanguagesFile = "config/languages.yaml"
+
+type languagesFile struct {
+	Languages map[string]models.Language `yaml:"languages" validate:"required,dive"`
+}
+
+// LoadLanguages reads the language registry from a YAML file.
+func LoadLanguages(filename string) (map[string]models.Language, error) {
+	if filename == "" {
+		filename = DefaultLanguagesFile
+	}
+
+	file := &languagesFile{}
+	if err := pkg.ReadFile(filename, file); err != nil {
+		return nil, err
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(file); err != nil {
+		return nil, err
+	}
+
+	languages := make(map[string]models.Language, len(file.Languages))
+	for name, language := range file.Languages {
+		for _, pattern := range language.NamePatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("language %s: invalid name pattern: %w", name, err)
+			}
+			if re.NumSubexp() < 1 {
+				return nil, fmt.Errorf("language %s: name pattern %q has no capture group", name, pattern)
+			}
+		}
+
+		language.Name = name
+		languages[name] = language
+	}
+
+	return languages, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadLanguagesShippedRegistry(t *testing.T) {
+	languages, err := LoadLanguages("languages.yaml")
+	if err != nil {
+		t.Fatalf("LoadLanguages: %v", err)
+	}
+
+	for _, name := range []string{"python3", "nodejs", "java", "cpp", "go"} {
+		lang, ok := languages[name]
+		if !ok {
+			t.Errorf("%s is missing", name)
+			continue
+		}
+		if lang.Name != name {
+			t.Errorf("%s is named %q", name, lang.Name)
+		}
+	}
+	if java := languages["java"]; len(java.Compile) == 0 || len(java.NamePatterns) == 0 {
+		t.Errorf("java has compile command %v and name patterns %v, want both", java.Compile, java.NamePatterns)
+	}
+}
+
+func TestLoadLanguagesRejectsInvalidEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr string
+	}{
+		{
+			name:    "missing run command",
+			entry:   "image: sandbox-python\n    fileName: main.py",
+			wantErr: "Run",
+		},
+		{
+			name:    "negative timeout",
+			entry:   "image: sandbox-python\n    fileName: main.py\n    run: [python3]\n    timeout: -1",
+			wantErr: "Timeout",
+		},
+		{
+			name:    "memory limit too low",
+			entry:   "image: sandbox-python\n    fileName: main.py\n    run: [python3]\n    limits: {memoryMB: 2}",
+			wantErr: "MemoryMB",
+		},
+		{
+			name:    "invalid name pattern",
+			entry:   "image: sandbox-python\n    fileName: main.py\n    run: [python3]\n    namePatterns: ['(']",
+			wantErr: "invalid name pattern",
+		},
+		{
+			name:    "name pattern without group",
+			entry:   "image: sandbox-python\n    fileName: main.py\n    run: [python3]\n    namePatterns: ['class \\w+']",
+			wantErr: "no capture group",
+		},
+	}
+
+	for _, test := range tests {
+		filename := writeFile(t, "languages.yaml", "languages:\n  python3:\n    label: Python\n    version: '3'\n    "+test.entry+"\n")
+		_, err := LoadLanguages(filename)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: got error %v, want one mentioning %q", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -1,24 +1,45 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
 
 	"ikurotime/code-engine/internal/models"
 	"ikurotime/code-engine/internal/services"
+	"ikurotime/code-engine/internal/store"
 )
 
+// userHeader names the user of a request for the submission history. It is
+// meant to be set by an authenticating proxy in front of the server.
+const userHeader = "X-User-ID"
+
 type Handler struct {
-	executor *services.Executor
-	logger   *log.Logger
+	executor        *services.Executor
+	jobs            *services.JobQueue
+	problems        store.ProblemStore
+	submissions     store.SubmissionStore
+	adminToken      string
+	maxRequestBytes int64
+	logger          *log.Logger
 }
 
-func NewHandler(executor *services.Executor, logger *log.Logger) *Handler {
+// NewHandler returns the handler of all routes. The problem and submission
+// routes need a store and the admin routes an admin token.
+func NewHandler(executor *services.Executor, jobs *services.JobQueue, db store.Store, adminToken string, maxRequestBytes int64, logger *log.Logger) *Handler {
+	if maxRequestBytes <= 0 {
+		maxRequestBytes = DefaultMaxRequestBytes
+	}
 	return &Handler{
-		executor: executor,
-		logger:   logger,
+		executor:        executor,
+		jobs:            jobs,
+		problems:        db,
+		submissions:     db,
+		adminToken:      adminToken,
+		maxRequestBytes: maxRequestBytes,
+		logger:          logger,
 	}
 }
 
@@ -36,42 +57,72 @@ func (h *Handler) Execute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//formdata
-	code := r.FormValue("code")
-	language := r.FormValue("language")
-
-	if code == "" || language == "" {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Code and language are required")
+	request, err := h.decodeExecuteRequest(w, r)
+	if err != nil {
+		h.writeRequestError(w, err)
 		return
 	}
 
-	request := models.ExecuteRequest{
-		Code:     code,
-		Language: language,
-	}
-
 	h.logger.Printf("Request: %+v", request)
 
-	output, err := h.executor.Execute(request)
+	response, err := h.executor.Execute(origin(r, ""), request)
 	if err != nil {
-		h.logger.Printf("Error executing code: %s, output: %s", err, output)
-
-		// Check if error is due to shutdown
-		if strings.Contains(err.Error(), "shutting down") {
-			h.writeErrorResponse(w, http.StatusServiceUnavailable, "Service is shutting down")
-			return
-		}
-
-		// Return execution error with output
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(models.ExecuteResponse{Output: output})
+		h.logger.Printf("Error executing code: %s", err)
+		h.writeExecutionError(w, err)
 		return
 	}
 
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// origin returns the request's context carrying who made the request, and
+// for which problem if any.
+func origin(r *http.Request, problemID string) context.Context {
+	return services.WithOrigin(r.Context(), services.Origin{User: r.Header.Get(userHeader), ProblemID: problemID})
+}
+
+// writeRequestError answers a request that could not be decoded.
+func (h *Handler) writeRequestError(w http.ResponseWriter, err error) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		h.writeErrorResponse(w, reqErr.status, reqErr.message)
+	} else {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+	}
+}
+
+// writeExecutionError answers a request the executor could not run.
+func (h *Handler) writeExecutionError(w http.ResponseWriter, err error) {
+	status, message := executionError(err)
+	h.writeErrorResponse(w, status, message)
+}
+
+// executionError returns the status and message describing an executor error.
+func executionError(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrShuttingDown):
+		return http.StatusServiceUnavailable, "Service is shutting down"
+	case errors.Is(err, services.ErrUnsupportedLanguage):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, services.ErrStdinTooLarge), errors.Is(err, services.ErrBundleTooLarge):
+		return http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, services.ErrInvalidFiles), errors.Is(err, services.ErrInvalidTests), errors.Is(err, services.ErrInvalidLimits):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, services.ErrPoolExhausted):
+		return http.StatusServiceUnavailable, "No sandbox available, try again later"
+	case errors.Is(err, services.ErrQueueFull):
+		return http.StatusServiceUnavailable, "Too many queued jobs, try again later"
+	case errors.Is(err, services.ErrCheckerFailed):
+		return http.StatusInternalServerError, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to execute code"
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, statusCode int, v any) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(models.ExecuteResponse{Output: output})
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
 }
 
 func (h *Handler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/internal/services"
+)
+
+// newTestHandler returns a handler whose sandboxes print the code they run
+// followed by their stdin.
+func newTestHandler(t *testing.T, maxRequestBytes int64) *Handler {
+	t.Helper()
+	rt := services.NewFakeRuntime()
+	rt.ExecFunc = func(c *services.FakeContainer, opts services.ExecOptions) (services.ExecResult, error) {
+		if opts.Stdout == nil {
+			return services.ExecResult{}, nil
+		}
+		io.WriteString(opts.Stdout, string(c.Files[opts.Cmd[1]]))
+		if opts.Stdin != nil {
+			io.Copy(opts.Stdout, opts.Stdin)
+		}
+		return services.ExecResult{}, nil
+	}
+	return newRuntimeHandler(t, rt, maxRequestBytes)
+}
+
+// newRuntimeHandler returns a handler running python3 on rt.
+func newRuntimeHandler(t *testing.T, rt services.Runtime, maxRequestBytes int64) *Handler {
+	t.Helper()
+	logger := log.New(io.Discard, "", 0)
+	executor := services.NewExecutor(rt, services.ExecutorConfig{
+		Languages: map[string]models.Language{
+			"python3": {Name: "python3", Image: "sandbox-python", FileName: "main.py", Run: []string{"python3", "{{file}}"}},
+		},
+		MaxConcurrent: 1,
+		Timeout:       time.Second,
+	}, logger)
+	jobs := services.NewJobQueue(executor, services.JobQueueConfig{}, logger)
+	t.Cleanup(jobs.Shutdown)
+	return NewHandler(executor, jobs, nil, "", maxRequestBytes, logger)
+}
+
+func multipartBody(t *testing.T, fields map[string]string) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body.String(), mw.FormDataContentType()
+}
+
+func TestExecuteDecodesRequest(t *testing.T) {
+	formBody, formType := multipartBody(t, map[string]string{"language": "python3", "code": "print(input())", "stdin": "hi"})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"language": "python3", "code": "print(input())", "stdin": "hi"}`,
+			status:      http.StatusOK,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "language=python3&code=print%28input%28%29%29&stdin=hi",
+			status:      http.StatusOK,
+		},
+		{
+			name:        "multipart form",
+			contentType: formType,
+			body:        formBody,
+			status:      http.StatusOK,
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/json",
+			body:        `{"language": "python3", "code": "print(1)", "lang": "python3"}`,
+			status:      http.StatusBadRequest,
+			response:    `json: unknown field "lang"`,
+		},
+		{
+			name:        "two objects",
+			contentType: "application/json",
+			body:        `{"language": "python3", "code": "print(1)"} {}`,
+			status:      http.StatusBadRequest,
+			response:    "Request body must contain a single JSON object",
+		},
+		{
+			name:        "malformed json",
+			contentType: "application/json",
+			body:        `{"language": "python3",`,
+			status:      http.StatusBadRequest,
+			response:    "Request body contains malformed JSON",
+		},
+		{
+			name:        "wrong type",
+			contentType: "application/json",
+			body:        `{"language": "python3", "code": 1}`,
+			status:      http.StatusBadRequest,
+			response:    `Invalid value for field "code"`,
+		},
+		{
+			name:        "empty json",
+			contentType: "application/json",
+			status:      http.StatusBadRequest,
+			response:    "Request body is empty",
+		},
+		{
+			name:        "missing code",
+			contentType: "application/json",
+			body:        `{"language": "python3"}`,
+			status:      http.StatusBadRequest,
+			response:    "Language and code or entrypoint are required",
+		},
+		{
+			name:        "json over the limit",
+			contentType: "application/json",
+			body:        `{"language": "python3", "code": "` + strings.Repeat("x", 1000) + `"}`,
+			status:      http.StatusRequestEntityTooLarge,
+			response:    "Request body exceeds 512 bytes",
+		},
+		{
+			name:        "form over the limit",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "language=python3&code=" + strings.Repeat("x", 1000),
+			status:      http.StatusRequestEntityTooLarge,
+			response:    "Request body exceeds 512 bytes",
+		},
+		{
+			name:        "unsupported type",
+			contentType: "text/plain",
+			body:        "print(1)",
+			status:      http.StatusUnsupportedMediaType,
+			response:    `Unsupported Content-Type "text/plain"`,
+		},
+	}
+
+	h := newTestHandler(t, 512)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/execute", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+			w := httptest.NewRecorder()
+			h.Execute(w, r)
+
+			if w.Code != test.status {
+				t.Fatalf("got status %d (%s), want %d", w.Code, w.Body.String(), test.status)
+			}
+			if test.status != http.StatusOK {
+				var response models.ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Error != test.response {
+					t.Errorf("got error %q (%v), want %q", response.Error, err, test.response)
+				}
+				return
+			}
+
+			var response models.ExecuteResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Status != models.StatusOK || response.Stdout != "print(input())hi" {
+				t.Errorf("got status %s and stdout %q, want the code run with the request's stdin", response.Status, response.Stdout)
+			}
+		})
+	}
+}
+
+func TestExecuteRejectsOtherMethods(t *testing.T) {
+	h := newTestHandler(t, 0)
+	w := httptest.NewRecorder()
+	h.Execute(w, httptest.NewRequest(http.MethodGet, "/execute", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -9,6 +9,6 @@ import (
 
 func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 	h.logger.Printf("%s %s", r.Method, r.URL.Path)
-	templ := templates.Layout(templates.Home("World"))
+	templ := templates.Layout(templates.Home(h.executor.Languages()))
 	templ.Render(context.Background(), w)
 }
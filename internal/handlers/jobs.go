@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"ikurotime/code-engine/internal/services"
+)
+
+// SubmitJob queues an execution and answers right away with the job, whose
+// state can then be polled with GetJob.
+func (h *Handler) SubmitJob(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if h.executor.IsShutdown() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Service is shutting down")
+		return
+	}
+
+	request, err := h.decodeExecuteRequest(w, r)
+	if err != nil {
+		h.writeRequestError(w, err)
+		return
+	}
+
+	job, err := h.jobs.Submit(origin(r, ""), request)
+	if err != nil {
+		h.logger.Printf("Error submitting job: %s", err)
+		h.writeExecutionError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	job, err := h.jobs.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeJobError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, job)
+}
+
+// CancelJob cancels a queued or running job. Finished jobs cannot be
+// cancelled and are answered with 409.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	job, err := h.jobs.Cancel(r.PathValue("id"))
+	if err != nil {
+		h.writeJobError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, job)
+}
+
+func (h *Handler) writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		h.writeErrorResponse(w, http.StatusNotFound, "Job not found")
+	case errors.Is(err, services.ErrJobFinished):
+		h.writeErrorResponse(w, http.StatusConflict, "Job already finished")
+	default:
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to access job")
+	}
+}
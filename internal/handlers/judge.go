@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// Judge runs a submission against test cases and answers with the verdicts.
+// A submission that fails is still answered with 200; only requests that
+// could not be judged at all are errors.
+func (h *Handler) Judge(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if h.executor.IsShutdown() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Service is shutting down")
+		return
+	}
+
+	var request models.JudgeRequest
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	if err := decodeJSON(r.Body, &request); err != nil {
+		h.writeRequestError(w, err)
+		return
+	}
+	if request.Language == "" || (request.Code == "" && request.Entrypoint == "") {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Language and code or entrypoint are required")
+		return
+	}
+
+	response, err := h.executor.Judge(origin(r, ""), request)
+	if err != nil {
+		h.logger.Printf("Error judging code: %s", err)
+		h.writeExecutionError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/internal/services"
+	"ikurotime/code-engine/internal/store"
+	"ikurotime/code-engine/templates"
+)
+
+var problemIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// ProblemsPage lists the problem catalog.
+func (h *Handler) ProblemsPage(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	problems, err := h.problems.ListProblems(r.Context())
+	if err != nil {
+		h.logger.Printf("Error listing problems: %s", err)
+		http.Error(w, "Failed to load problems", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout(templates.Problems(problems)).Render(r.Context(), w)
+}
+
+// ProblemPage shows a problem with its sample tests and an editor to submit
+// a solution.
+func (h *Handler) ProblemPage(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	problem, err := h.problems.GetProblem(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		h.logger.Printf("Error loading problem: %s", err)
+		http.Error(w, "Failed to load problem", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout(templates.Problem(problem, h.problemLanguages(problem))).Render(r.Context(), w)
+}
+
+// SubmitProblem judges a solution against the sample and hidden tests of a
+// problem. Output of hidden tests is left out of the response.
+func (h *Handler) SubmitProblem(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if h.executor.IsShutdown() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Service is shutting down")
+		return
+	}
+
+	var submission models.ProblemSubmission
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	if err := decodeJSON(r.Body, &submission); err != nil {
+		h.writeRequestError(w, err)
+		return
+	}
+	if submission.Language == "" || submission.Code == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Language and code are required")
+		return
+	}
+
+	problem, err := h.problems.GetProblem(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+	if len(problem.Languages) > 0 && !slices.Contains(problem.Languages, submission.Language) {
+		h.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Language %s is not allowed for this problem", submission.Language))
+		return
+	}
+
+	response, err := h.executor.Judge(origin(r, problem.ID), models.JudgeRequest{
+		Language:      submission.Language,
+		Code:          submission.Code,
+		TestCases:     append(slices.Clip(problem.SampleTests), problem.HiddenTests...),
+		Comparison:    problem.Comparison,
+		Checker:       problem.Checker,
+		TimeLimitMs:   problem.TimeLimitMs,
+		MemoryLimitMB: problem.MemoryLimitMB,
+	})
+	if err != nil {
+		h.logger.Printf("Error judging submission for problem %s: %s", problem.ID, err)
+		h.writeExecutionError(w, err)
+		return
+	}
+
+	for i := len(problem.SampleTests); i < len(response.Tests); i++ {
+		response.Tests[i].Stdout = ""
+		response.Tests[i].Stderr = ""
+		response.Tests[i].CheckerMessage = ""
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// problemLanguages returns the supported languages allowed for a problem.
+func (h *Handler) problemLanguages(problem models.Problem) []models.Language {
+	var languages []models.Language
+	for _, language := range h.executor.Languages() {
+		if len(problem.Languages) == 0 || slices.Contains(problem.Languages, language.Name) {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// ListProblems returns all problems without their tests to admins.
+func (h *Handler) ListProblems(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	problems, err := h.problems.ListProblems(r.Context())
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, problems)
+}
+
+// GetProblem returns a problem, including its hidden tests, to admins.
+func (h *Handler) GetProblem(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	problem, err := h.problems.GetProblem(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, problem)
+}
+
+func (h *Handler) CreateProblem(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	problem, err := h.decodeProblem(w, r)
+	if err != nil {
+		h.writeRequestError(w, err)
+		return
+	}
+
+	problem, err = h.problems.CreateProblem(r.Context(), problem)
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/admin/problems/"+problem.ID)
+	h.writeJSON(w, http.StatusCreated, problem)
+}
+
+// UpdateProblem replaces a problem with the one in the body, whose ID is
+// taken from the path.
+func (h *Handler) UpdateProblem(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	problem, err := h.decodeProblem(w, r)
+	if err != nil {
+		h.writeRequestError(w, err)
+		return
+	}
+
+	problem, err = h.problems.UpdateProblem(r.Context(), problem)
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, problem)
+}
+
+func (h *Handler) DeleteProblem(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	if err := h.problems.DeleteProblem(r.Context(), r.PathValue("id")); err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeAdmin answers requests without the admin token and reports
+// whether the request may go on.
+func (h *Handler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.adminToken == "" {
+		h.writeErrorResponse(w, http.StatusForbidden, "Admin API is disabled")
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Invalid admin token")
+		return false
+	}
+	return true
+}
+
+// decodeProblem reads and validates a problem. On PUT the ID comes from the
+// path and may be left out of the body.
+func (h *Handler) decodeProblem(w http.ResponseWriter, r *http.Request) (models.Problem, error) {
+	var problem models.Problem
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	if err := decodeJSON(r.Body, &problem); err != nil {
+		return problem, err
+	}
+
+	if id := r.PathValue("id"); id != "" {
+		if problem.ID != "" && problem.ID != id {
+			return problem, &requestError{http.StatusBadRequest, "Problem ID does not match the path"}
+		}
+		problem.ID = id
+	}
+
+	return problem, h.validateProblem(problem)
+}
+
+func (h *Handler) validateProblem(problem models.Problem) error {
+	switch {
+	case !problemIDPattern.MatchString(problem.ID):
+		return &requestError{http.StatusBadRequest, "Problem ID must be 1 to 64 lowercase letters, digits and dashes"}
+	case problem.Title == "":
+		return &requestError{http.StatusBadRequest, "Title is required"}
+	case problem.TimeLimitMs < 0 || problem.MemoryLimitMB < 0:
+		return &requestError{http.StatusBadRequest, "Limits cannot be negative"}
+	case problem.MemoryLimitMB > 0 && problem.MemoryLimitMB < services.MinMemoryMB:
+		return &requestError{http.StatusBadRequest, fmt.Sprintf("Memory limit must be at least %d MB", services.MinMemoryMB)}
+	case len(problem.SampleTests)+len(problem.HiddenTests) == 0:
+		return &requestError{http.StatusBadRequest, "At least one test is required"}
+	case len(problem.SampleTests)+len(problem.HiddenTests) > services.MaxTestCases:
+		return &requestError{http.StatusBadRequest, fmt.Sprintf("At most %d tests are allowed", services.MaxTestCases)}
+	}
+
+	supported := make(map[string]bool)
+	for _, language := range h.executor.Languages() {
+		supported[language.Name] = true
+	}
+	for _, language := range problem.Languages {
+		if !supported[language] {
+			return &requestError{http.StatusBadRequest, fmt.Sprintf("Unsupported language %s", language)}
+		}
+	}
+	if checker := problem.Checker; checker != nil {
+		if !supported[checker.Language] {
+			return &requestError{http.StatusBadRequest, fmt.Sprintf("Unsupported checker language %s", checker.Language)}
+		}
+		if checker.Code == "" && checker.Entrypoint == "" {
+			return &requestError{http.StatusBadRequest, "Checker code or entrypoint is required"}
+		}
+	}
+	return nil
+}
+
+func (h *Handler) writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		h.writeErrorResponse(w, http.StatusNotFound, "Problem not found")
+	case errors.Is(err, store.ErrExists):
+		h.writeErrorResponse(w, http.StatusConflict, "Problem already exists")
+	default:
+		h.logger.Printf("Error accessing problem catalog: %s", err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to access problem catalog")
+	}
+}
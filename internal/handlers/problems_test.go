@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/internal/store"
+)
+
+// memoryStore keeps problems in memory.
+type memoryStore struct {
+	mu       sync.Mutex
+	problems map[string]models.Problem
+}
+
+func (s *memoryStore) ListProblems(ctx context.Context) ([]models.Problem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	problems := []models.Problem{}
+	for _, problem := range s.problems {
+		problem.SampleTests, problem.HiddenTests = nil, nil
+		problems = append(problems, problem)
+	}
+	slices.SortFunc(problems, func(a, b models.Problem) int { return strings.Compare(a.ID, b.ID) })
+	return problems, nil
+}
+
+func (s *memoryStore) GetProblem(ctx context.Context, id string) (models.Problem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	problem, ok := s.problems[id]
+	if !ok {
+		return models.Problem{}, store.ErrNotFound
+	}
+	return problem, nil
+}
+
+func (s *memoryStore) CreateProblem(ctx context.Context, problem models.Problem) (models.Problem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.problems[problem.ID]; ok {
+		return models.Problem{}, store.ErrExists
+	}
+	s.problems[problem.ID] = problem
+	return problem, nil
+}
+
+func (s *memoryStore) UpdateProblem(ctx context.Context, problem models.Problem) (models.Problem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.problems[problem.ID]; !ok {
+		return models.Problem{}, store.ErrNotFound
+	}
+	s.problems[problem.ID] = problem
+	return problem, nil
+}
+
+func (s *memoryStore) DeleteProblem(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.problems[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.problems, id)
+	return nil
+}
+
+// newProblemsServer routes the problem endpoints to a handler with an empty
+// catalog and the admin token "secret".
+func newProblemsServer(t *testing.T) *http.ServeMux {
+	t.Helper()
+	h := newTestHandler(t, 0)
+	h.problems = &memoryStore{problems: make(map[string]models.Problem)}
+	h.adminToken = "secret"
+
+	router := http.NewServeMux()
+	router.HandleFunc("POST /problems/{id}/submissions", h.SubmitProblem)
+	router.HandleFunc("GET /admin/problems", h.ListProblems)
+	router.HandleFunc("POST /admin/problems", h.CreateProblem)
+	router.HandleFunc("GET /admin/problems/{id}", h.GetProblem)
+	router.HandleFunc("PUT /admin/problems/{id}", h.UpdateProblem)
+	router.HandleFunc("DELETE /admin/problems/{id}", h.DeleteProblem)
+	return router
+}
+
+func serve(router http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+const twoSum = `{
+	"id": "two-sum",
+	"title": "Two Sum",
+	"languages": ["python3"],
+	"sampleTests": [{"input": "1 2", "expected": "solve()1 2"}],
+	"hiddenTests": [{"input": "3 4", "expected": "solve()3 4"}]
+}`
+
+func TestAdminRequiresToken(t *testing.T) {
+	router := newProblemsServer(t)
+
+	for _, token := range []string{"", "wrong"} {
+		w := serve(router, http.MethodGet, "/admin/problems", token, "")
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("token %q: got status %d with WWW-Authenticate %q, want 401 with Bearer", token, w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+	if w := serve(router, http.MethodPost, "/admin/problems", "wrong", twoSum); w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d creating a problem with a wrong token, want 401", w.Code)
+	}
+	if w := serve(router, http.MethodGet, "/admin/problems", "secret", ""); w.Code != http.StatusOK {
+		t.Errorf("got status %d (%s) with the admin token, want 200", w.Code, w.Body.String())
+	}
+
+	h := newTestHandler(t, 0)
+	w := httptest.NewRecorder()
+	h.ListProblems(w, httptest.NewRequest(http.MethodGet, "/admin/problems", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("got status %d without an admin token configured, want 403", w.Code)
+	}
+}
+
+func TestAdminManagesProblems(t *testing.T) {
+	router := newProblemsServer(t)
+
+	w := serve(router, http.MethodPost, "/admin/problems", "secret", twoSum)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/admin/problems/two-sum" {
+		t.Fatalf("got status %d at %q (%s), want 201 at /admin/problems/two-sum", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{http.MethodPost, "/admin/problems", twoSum, http.StatusConflict},
+		{http.MethodPost, "/admin/problems", `{"id": "Two Sum", "title": "Two Sum"}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/problems", `{"id": "empty", "title": "Empty"}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/problems/two-sum", `{"id": "three-sum", "title": "Two Sum"}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/problems/two-sum", strings.Replace(twoSum, "Two Sum", "2Sum", 1), http.StatusOK},
+		{http.MethodGet, "/admin/problems/two-sum", "", http.StatusOK},
+		{http.MethodPut, "/admin/problems/missing", strings.Replace(twoSum, `"two-sum"`, `"missing"`, 1), http.StatusNotFound},
+		{http.MethodDelete, "/admin/problems/two-sum", "", http.StatusNoContent},
+		{http.MethodGet, "/admin/problems/two-sum", "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		w := serve(router, test.method, test.target, "secret", test.body)
+		if w.Code != test.status {
+			t.Errorf("%s %s: got status %d (%s), want %d", test.method, test.target, w.Code, w.Body.String(), test.status)
+		}
+		if test.method == http.MethodGet && w.Code == http.StatusOK {
+			var problem models.Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil || problem.Title != "2Sum" || len(problem.HiddenTests) != 1 {
+				t.Errorf("got problem %+v (%v), want the updated problem with its hidden test", problem, err)
+			}
+		}
+	}
+}
+
+func TestSubmitProblem(t *testing.T) {
+	router := newProblemsServer(t)
+	if w := serve(router, http.MethodPost, "/admin/problems", "secret", twoSum); w.Code != http.StatusCreated {
+		t.Fatalf("got status %d (%s) creating the problem", w.Code, w.Body.String())
+	}
+
+	w := serve(router, http.MethodPost, "/problems/two-sum/submissions", "", `{"language": "python3", "code": "solve()"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d (%s), want 200", w.Code, w.Body.String())
+	}
+	var response models.JudgeResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Verdict != models.VerdictAccepted || len(response.Tests) != 2 {
+		t.Fatalf("got verdict %s with %d tests, want AC with 2", response.Verdict, len(response.Tests))
+	}
+	if response.Tests[0].Stdout != "solve()1 2" || response.Tests[1].Stdout != "" {
+		t.Errorf("got outputs %q and %q, want the sample's output only", response.Tests[0].Stdout, response.Tests[1].Stdout)
+	}
+
+	tests := map[string]struct {
+		target string
+		body   string
+		status int
+	}{
+		"unknown problem":     {"/problems/missing/submissions", `{"language": "python3", "code": "solve()"}`, http.StatusNotFound},
+		"missing code":        {"/problems/two-sum/submissions", `{"language": "python3"}`, http.StatusBadRequest},
+		"disallowed language": {"/problems/two-sum/submissions", `{"language": "go", "code": "solve()"}`, http.StatusBadRequest},
+	}
+	for name, test := range tests {
+		if w := serve(router, http.MethodPost, test.target, "", test.body); w.Code != test.status {
+			t.Errorf("%s: got status %d (%s), want %d", name, w.Code, w.Body.String(), test.status)
+		}
+	}
+}
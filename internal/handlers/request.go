@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// DefaultMaxRequestBytes is used when no request body limit is configured.
+const DefaultMaxRequestBytes = 2 << 20
+
+// requestError is a malformed request together with the status to answer it with.
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// decodeExecuteRequest reads an ExecuteRequest from a JSON body or, for the
+// templ UI, from form values.
+func (h *Handler) decodeExecuteRequest(w http.ResponseWriter, r *http.Request) (models.ExecuteRequest, error) {
+	var req models.ExecuteRequest
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+
+	mediaType := ""
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return req, &requestError{http.StatusUnsupportedMediaType, "Malformed Content-Type header"}
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		if err := decodeJSON(r.Body, &req); err != nil {
+			return req, err
+		}
+	case "", "application/x-www-form-urlencoded", "multipart/form-data":
+		// ParseMultipartForm ignores errors reading url-encoded bodies
+		var err error
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(h.maxRequestBytes)
+		} else {
+			err = r.ParseForm()
+		}
+		if err != nil {
+			return req, bodyError(err)
+		}
+		req.Code = r.FormValue("code")
+		req.Language = r.FormValue("language")
+		req.Stdin = r.FormValue("stdin")
+		req.Entrypoint = r.FormValue("entrypoint")
+		files, err := formFiles(r)
+		if err != nil {
+			return req, err
+		}
+		req.Files = files
+	default:
+		return req, &requestError{http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Type %q", mediaType)}
+	}
+
+	if req.Language == "" || (req.Code == "" && req.Entrypoint == "") {
+		return req, &requestError{http.StatusBadRequest, "Language and code or entrypoint are required"}
+	}
+
+	return req, nil
+}
+
+// formFiles returns the files uploaded as "files" parts of a multipart form,
+// stored under their file names.
+func formFiles(r *http.Request) ([]models.File, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+
+	var files []models.File
+	for _, header := range r.MultipartForm.File["files"] {
+		f, err := header.Open()
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, "Failed to read uploaded file"}
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, "Failed to read uploaded file"}
+		}
+		files = append(files, models.File{Path: header.Filename, Content: string(content)})
+	}
+	return files, nil
+}
+
+// decodeJSON decodes exactly one JSON object without unknown fields.
+func decodeJSON(body io.Reader, v any) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return bodyError(err)
+	}
+	if dec.More() {
+		return &requestError{http.StatusBadRequest, "Request body must contain a single JSON object"}
+	}
+	return nil
+}
+
+func bodyError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &requestError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit)}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &requestError{http.StatusBadRequest, "Request body contains malformed JSON"}
+	case errors.As(err, &typeErr):
+		return &requestError{http.StatusBadRequest, fmt.Sprintf("Invalid value for field %q", typeErr.Field)}
+	case errors.Is(err, io.EOF):
+		return &requestError{http.StatusBadRequest, "Request body is empty"}
+	default:
+		// e.g. unknown fields, which encoding/json reports as a plain error
+		return &requestError{http.StatusBadRequest, err.Error()}
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// sessionWriteTimeout bounds every write to a session's WebSocket, so a
+// client that stopped reading cannot block the session.
+const sessionWriteTimeout = 10 * time.Second
+
+// The default origin check only accepts connections from pages served by
+// this server.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// sessionMessage is a message of the session protocol. Clients send one
+// "start" message with the language and optional code or files, then "input"
+// messages with keystrokes. The server sends "output" messages with
+// terminal output and ends with one "exit" or "error" message.
+type sessionMessage struct {
+	Type       string                 `json:"type"`
+	Language   string                 `json:"language,omitempty"`
+	Code       string                 `json:"code,omitempty"`
+	Files      []models.File          `json:"files,omitempty"`
+	Entrypoint string                 `json:"entrypoint,omitempty"`
+	Data       string                 `json:"data,omitempty"`
+	ExitCode   *int                   `json:"exitCode,omitempty"`
+	Status     models.ExecutionStatus `json:"status,omitempty"`
+	Timings    *models.Timings        `json:"timings,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Session runs an interactive program, or the language's REPL, over a
+// WebSocket. The session ends when the program exits, a session timeout
+// expires or the client disconnects.
+func (h *Handler) Session(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if h.executor.IsShutdown() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Service is shutting down")
+		return
+	}
+	if !h.executor.SupportsSessions() {
+		h.writeErrorResponse(w, http.StatusNotImplemented, "Terminal sessions need the api runtime backend")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		h.logger.Printf("Failed to upgrade session: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(h.maxRequestBytes)
+
+	ws := &sessionConn{conn: conn}
+
+	var start sessionMessage
+	if err := conn.ReadJSON(&start); err != nil || start.Type != "start" {
+		ws.close(sessionMessage{Type: "error", Error: "Expected a start message"})
+		return
+	}
+	if start.Language == "" {
+		ws.close(sessionMessage{Type: "error", Error: "Language is required"})
+		return
+	}
+
+	// The request context is not cancelled when a hijacked client goes away,
+	// so the session is cancelled by the input loop instead.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input, inputWriter := io.Pipe()
+	defer input.Close()
+	go relaySessionInput(conn, inputWriter, cancel)
+
+	response, err := h.executor.RunSession(ctx, models.SessionRequest{
+		Language:   start.Language,
+		Code:       start.Code,
+		Files:      start.Files,
+		Entrypoint: start.Entrypoint,
+	}, input, ws)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		h.logger.Printf("Error running session: %s", err)
+		ws.close(sessionMessage{Type: "error", Error: sessionError(err)})
+		return
+	}
+
+	ws.flush()
+	ws.close(sessionMessage{
+		Type:     "exit",
+		ExitCode: &response.ExitCode,
+		Status:   response.Status,
+		Timings:  &response.Timings,
+	})
+}
+
+// relaySessionInput feeds "input" messages to the program until the client
+// disconnects, which cancels the session.
+func relaySessionInput(conn *websocket.Conn, input *io.PipeWriter, cancel context.CancelFunc) {
+	defer cancel()
+	defer input.Close()
+
+	for {
+		var msg sessionMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Type != "input" {
+			continue
+		}
+		if _, err := io.WriteString(input, msg.Data); err != nil {
+			return
+		}
+	}
+}
+
+// sessionError describes why a session could not be run.
+func sessionError(err error) string {
+	switch {
+	case errors.Is(err, services.ErrNoRepl), errors.Is(err, services.ErrTTYUnsupported), errors.Is(err, services.ErrTooManySessions):
+		return err.Error()
+	default:
+		_, message := executionError(err)
+		return message
+	}
+}
+
+// sessionConn sends terminal output as "output" messages. It is the
+// session's output writer and serializes writes to the WebSocket.
+type sessionConn struct {
+	conn    *websocket.Conn
+	mu      sync.Mutex
+	pending []byte
+}
+
+func (s *sessionConn) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := append(s.pending, p...)
+	complete, rest := splitUTF8(data)
+	s.pending = append([]byte(nil), rest...)
+	if len(complete) == 0 {
+		return len(p), nil
+	}
+	if err := s.send(sessionMessage{Type: "output", Data: string(complete)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush sends output held back by Write.
+func (s *sessionConn) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) > 0 {
+		s.send(sessionMessage{Type: "output", Data: string(s.pending)})
+		s.pending = nil
+	}
+}
+
+// close sends a final message and closes the WebSocket normally.
+func (s *sessionConn) close(msg sessionMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.send(msg)
+	s.conn.SetWriteDeadline(time.Now().Add(sessionWriteTimeout))
+	s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// send writes a message. s.mu must be held.
+func (s *sessionConn) send(msg sessionMessage) error {
+	s.conn.SetWriteDeadline(time.Now().Add(sessionWriteTimeout))
+	return s.conn.WriteJSON(msg)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ikurotime/code-engine/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// greeter asks for a name on a terminal and greets it.
+func greeter(c *services.FakeContainer, opts services.ExecOptions) (services.ExecResult, error) {
+	if !opts.Tty {
+		return services.ExecResult{}, nil
+	}
+	opts.Stdout.Write([]byte("Name? "))
+	name, err := bufio.NewReader(opts.Stdin).ReadString('\r')
+	if err != nil {
+		return services.ExecResult{ExitCode: 1}, nil
+	}
+	opts.Stdout.Write([]byte("Hi " + strings.TrimSuffix(name, "\r") + "\r\n"))
+	return services.ExecResult{}, nil
+}
+
+// dialSession connects to the session endpoint of h.
+func dialSession(t *testing.T, h *Handler) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(h.Session))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSessionRunsProgramOnTerminal(t *testing.T) {
+	rt := services.NewFakeRuntime()
+	rt.ExecFunc = greeter
+	conn := dialSession(t, newRuntimeHandler(t, rt, 1024))
+
+	if err := conn.WriteJSON(sessionMessage{Type: "start", Language: "python3", Code: "print('Hi', input('Name? '))"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if err := conn.WriteJSON(sessionMessage{Type: "input", Data: "Ada\r"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var output strings.Builder
+	for {
+		var msg sessionMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON: %v", err)
+		}
+		if msg.Type == "output" {
+			output.WriteString(msg.Data)
+			continue
+		}
+		if msg.Type != "exit" || msg.Status != "ok" || msg.ExitCode == nil || *msg.ExitCode != 0 {
+			t.Errorf("got final message %+v, want an exit with status ok", msg)
+		}
+		break
+	}
+	if output.String() != "Name? Hi Ada\r\n" {
+		t.Errorf("got terminal output %q, want the prompt and greeting", output.String())
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	rt := services.NewFakeRuntime()
+	rt.ExecFunc = greeter
+	conn := dialSession(t, newRuntimeHandler(t, rt, 1024))
+
+	// python3 has no REPL in the test registry
+	if err := conn.WriteJSON(sessionMessage{Type: "start", Language: "python3"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var msg sessionMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if msg.Type != "error" || msg.Error != "language has no REPL: python3" {
+		t.Errorf("got message %+v, want an error naming the missing REPL", msg)
+	}
+}
+
+func TestSessionNeedsTerminalRuntime(t *testing.T) {
+	rt := services.NewFakeRuntime()
+	rt.NoTTY = true
+	h := newRuntimeHandler(t, rt, 1024)
+
+	w := httptest.NewRecorder()
+	h.Session(w, httptest.NewRequest(http.MethodGet, "/sessions", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("got status %d, want %d before upgrading", w.Code, http.StatusNotImplemented)
+	}
+}
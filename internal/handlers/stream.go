@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// streamResult is the final event of a stream.
+type streamResult struct {
+	ExitCode  int                    `json:"exitCode"`
+	Status    models.ExecutionStatus `json:"status"`
+	Truncated bool                   `json:"truncated"`
+	Usage     models.Usage           `json:"usage"`
+	Timings   models.Timings         `json:"timings"`
+}
+
+// ExecuteStream runs code like Execute but sends its output as Server-Sent
+// Events while it runs. "compile", "stdout" and "stderr" events carry
+// JSON-encoded chunks of output; the stream ends with a "result" event
+// holding the exit code, status and timings, or with an "error" event if the
+// code could not be run. Requests are checked before the stream starts, so
+// invalid ones get the same error statuses as Execute.
+func (h *Handler) ExecuteStream(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if h.executor.IsShutdown() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Service is shutting down")
+		return
+	}
+
+	request, err := h.decodeExecuteRequest(w, r)
+	if err != nil {
+		h.writeRequestError(w, err)
+		return
+	}
+
+	if err := h.executor.Validate(request); err != nil {
+		h.writeExecutionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	events := &eventWriter{w: w, rc: http.NewResponseController(w), pending: make(map[string][]byte)}
+	events.flush()
+
+	response, err := h.executor.ExecuteStream(origin(r, ""), request, events.output)
+	events.flushOutput()
+	if err != nil {
+		h.logger.Printf("Error executing code: %s", err)
+		_, message := executionError(err)
+		events.send("error", models.ErrorResponse{Error: message})
+		return
+	}
+
+	events.send("result", streamResult{
+		ExitCode:  response.ExitCode,
+		Status:    response.Status,
+		Truncated: response.Truncated,
+		Usage:     response.Usage,
+		Timings:   response.Timings,
+	})
+}
+
+// eventWriter writes Server-Sent Events and flushes each one to the client.
+type eventWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+	// pending holds incomplete UTF-8 sequences at the end of a chunk, which
+	// are sent with the next chunk of the same stream.
+	pending map[string][]byte
+}
+
+// output sends a chunk of program output. It is a services.OutputFunc.
+func (e *eventWriter) output(stream string, data []byte) {
+	data = append(e.pending[stream], data...)
+	complete, rest := splitUTF8(data)
+	e.pending[stream] = append([]byte(nil), rest...)
+	if len(complete) > 0 {
+		e.send(stream, string(complete))
+	}
+}
+
+// flushOutput sends whatever output is still pending.
+func (e *eventWriter) flushOutput() {
+	for stream, data := range e.pending {
+		if len(data) > 0 {
+			e.send(stream, string(data))
+		}
+	}
+	clear(e.pending)
+}
+
+func (e *eventWriter) send(event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, data)
+	e.flush()
+}
+
+func (e *eventWriter) flush() {
+	// Errors mean the client is gone, which cancels the execution anyway
+	e.rc.Flush()
+}
+
+// splitUTF8 splits an incomplete UTF-8 sequence off the end of p.
+func splitUTF8(p []byte) (complete, rest []byte) {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return p[:i], p[i:]
+			}
+			break
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// event is a Server-Sent Event read back from a response.
+type event struct {
+	name string
+	data string
+}
+
+func readEvents(t *testing.T, body string) []event {
+	t.Helper()
+	var events []event
+	for _, block := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		name, data, ok := strings.Cut(block, "\n")
+		if !ok || !strings.HasPrefix(name, "event: ") || !strings.HasPrefix(data, "data: ") {
+			t.Fatalf("malformed event %q", block)
+		}
+		events = append(events, event{strings.TrimPrefix(name, "event: "), strings.TrimPrefix(data, "data: ")})
+	}
+	return events
+}
+
+func TestExecuteStreamSendsOutputThenResult(t *testing.T) {
+	h := newTestHandler(t, 0)
+	r := httptest.NewRequest(http.MethodPost, "/execute/stream", strings.NewReader(`{"language": "python3", "code": "print(input())", "stdin": "hi"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ExecuteStream(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got status %d and Content-Type %q, want an event stream", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	events := readEvents(t, w.Body.String())
+	var stdout strings.Builder
+	for _, e := range events[:len(events)-1] {
+		var chunk string
+		if e.name != "stdout" || json.Unmarshal([]byte(e.data), &chunk) != nil {
+			t.Fatalf("got event %q with data %s before the result, want stdout chunks", e.name, e.data)
+		}
+		stdout.WriteString(chunk)
+	}
+	if stdout.String() != "print(input())hi" {
+		t.Errorf("streamed stdout %q, want the code run with the request's stdin", stdout.String())
+	}
+
+	last := events[len(events)-1]
+	var result streamResult
+	if last.name != "result" || json.Unmarshal([]byte(last.data), &result) != nil {
+		t.Fatalf("got final event %q with data %s, want the result", last.name, last.data)
+	}
+	if result.Status != "ok" || result.ExitCode != 0 {
+		t.Errorf("got result %+v, want status ok and exit code 0", result)
+	}
+}
+
+func TestExecuteStreamRejectsInvalidRequests(t *testing.T) {
+	h := newTestHandler(t, 0)
+	r := httptest.NewRequest(http.MethodPost, "/execute/stream", strings.NewReader(`{"language": "cobol", "code": "DISPLAY 1"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ExecuteStream(w, r)
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") == "text/event-stream" {
+		t.Errorf("got status %d and Content-Type %q, want %d before any stream starts", w.Code, w.Header().Get("Content-Type"), http.StatusBadRequest)
+	}
+}
+
+func TestEventWriterKeepsRunesWhole(t *testing.T) {
+	w := httptest.NewRecorder()
+	events := &eventWriter{w: w, rc: http.NewResponseController(w), pending: make(map[string][]byte)}
+	events.output("stdout", []byte("caf\xc3"))
+	events.output("stdout", []byte("\xa9!"))
+	events.flushOutput()
+
+	want := "event: stdout\ndata: \"caf\"\n\n" +
+		"event: stdout\ndata: \"é!\"\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got events %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/internal/store"
+)
+
+// Page sizes of the submission history.
+const (
+	defaultSubmissionsLimit = 50
+	maxSubmissionsLimit     = 200
+)
+
+// ListSubmissions returns the submission history to admins, newest first,
+// filtered by the user, language, problem, status and verdict query
+// parameters. Pages hold up to limit submissions; the next one is fetched
+// with before set to the nextBefore of the response.
+func (h *Handler) ListSubmissions(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("%s %s", r.Method, r.URL.Path)
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.SubmissionFilter{
+		User:      query.Get("user"),
+		Language:  query.Get("language"),
+		ProblemID: query.Get("problem"),
+		Status:    models.ExecutionStatus(query.Get("status")),
+		Verdict:   models.Verdict(query.Get("verdict")),
+	}
+
+	limit := defaultSubmissionsLimit
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSubmissionsLimit {
+			h.writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxSubmissionsLimit))
+			return
+		}
+		limit = n
+	}
+	if s := query.Get("before"); s != "" {
+		before, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || before < 1 {
+			h.writeErrorResponse(w, http.StatusBadRequest, "before must be a submission ID")
+			return
+		}
+		filter.Before = before
+	}
+
+	// One more than asked for tells whether there is a next page
+	filter.Limit = limit + 1
+	submissions, err := h.submissions.ListSubmissions(r.Context(), filter)
+	if err != nil {
+		h.logger.Printf("Error listing submissions: %s", err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to access submission history")
+		return
+	}
+
+	page := models.SubmissionPage{Submissions: submissions}
+	if len(submissions) > limit {
+		page.Submissions = submissions[:limit]
+		page.NextBefore = submissions[limit-1].ID
+	}
+	h.writeJSON(w, http.StatusOK, page)
+}
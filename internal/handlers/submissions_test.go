@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+	"ikurotime/code-engine/internal/store"
+)
+
+// historyStore holds submissions 1 to count and remembers the last filter
+// it was asked for. It ignores every field of the filter but Before and
+// Limit.
+type historyStore struct {
+	count  int64
+	filter store.SubmissionFilter
+}
+
+func (s *historyStore) CreateSubmission(ctx context.Context, submission models.Submission) (models.Submission, error) {
+	s.count++
+	submission.ID = s.count
+	return submission, nil
+}
+
+func (s *historyStore) ListSubmissions(ctx context.Context, filter store.SubmissionFilter) ([]models.Submission, error) {
+	s.filter = filter
+	id := s.count
+	if filter.Before > 0 {
+		id = min(id, filter.Before-1)
+	}
+	submissions := []models.Submission{}
+	for ; id > 0 && len(submissions) < filter.Limit; id-- {
+		submissions = append(submissions, models.Submission{ID: id})
+	}
+	return submissions, nil
+}
+
+func TestListSubmissions(t *testing.T) {
+	h := newTestHandler(t, 0)
+	history := &historyStore{count: 5}
+	h.submissions = history
+	h.adminToken = "secret"
+
+	list := func(query string) (*httptest.ResponseRecorder, models.SubmissionPage) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/admin/submissions?"+query, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ListSubmissions(w, r)
+		var page models.SubmissionPage
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w, page
+	}
+
+	w, page := list("user=ada&language=python3&problem=two-sum&status=ok&verdict=AC")
+	want := store.SubmissionFilter{User: "ada", Language: "python3", ProblemID: "two-sum", Status: models.StatusOK, Verdict: models.VerdictAccepted, Limit: defaultSubmissionsLimit + 1}
+	if w.Code != http.StatusOK || history.filter != want {
+		t.Errorf("got status %d with filter %+v, want 200 with %+v", w.Code, history.filter, want)
+	}
+	if len(page.Submissions) != 5 || page.NextBefore != 0 {
+		t.Errorf("got %d submissions and next before %d, want all 5 on one page", len(page.Submissions), page.NextBefore)
+	}
+
+	var ids []int64
+	for query := "limit=2"; ; {
+		w, page := list(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d (%s), want 200", w.Code, w.Body.String())
+		}
+		for _, submission := range page.Submissions {
+			ids = append(ids, submission.ID)
+		}
+		if page.NextBefore == 0 {
+			break
+		}
+		query = "limit=2&before=" + strconv.FormatInt(page.NextBefore, 10)
+	}
+	if want := []int64{5, 4, 3, 2, 1}; !slices.Equal(ids, want) {
+		t.Errorf("got submissions %v over all pages, want %v", ids, want)
+	}
+
+	for _, query := range []string{"limit=0", "limit=201", "limit=x", "before=0", "before=x"} {
+		if w, _ := list(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", query, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ListSubmissions(w, httptest.NewRequest(http.MethodGet, "/admin/submissions", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d without the admin token, want 401", w.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+// Verdict is the outcome of judging a submission against a test case.
+type Verdict string
+
+const (
+	VerdictAccepted            Verdict = "AC"
+	VerdictPartiallyAccepted   Verdict = "PA"
+	VerdictWrongAnswer         Verdict = "WA"
+	VerdictTimeLimitExceeded   Verdict = "TLE"
+	VerdictMemoryLimitExceeded Verdict = "MLE"
+	VerdictOutputLimitExceeded Verdict = "OLE"
+	VerdictRuntimeError        Verdict = "RE"
+	VerdictCompileError        Verdict = "CE"
+)
+
+// JudgeRequest runs a submission, given like in ExecuteRequest, against
+// test cases.
+type JudgeRequest struct {
+	Language   string     `json:"language"`
+	Code       string     `json:"code"`
+	Files      []File     `json:"files"`
+	Entrypoint string     `json:"entrypoint"`
+	TestCases  []TestCase `json:"testCases"`
+	Comparison Comparison `json:"comparison"`
+	// Checker, when set, judges the output instead of Comparison.
+	Checker *Checker `json:"checker,omitempty"`
+	// TimeLimitMs, when positive, limits every test case instead of the
+	// language's timeout. It may not exceed the timeout.
+	TimeLimitMs int `json:"timeLimitMs,omitempty"`
+	// MemoryLimitMB, when positive, limits the memory of the sandbox while
+	// it runs the test cases instead of the language's limit, which it may
+	// not exceed. Runs killed for exceeding it are judged MLE.
+	MemoryLimitMB int `json:"memoryLimitMb,omitempty"`
+	// StopOnFailure skips the remaining test cases after the first one
+	// that is not accepted.
+	StopOnFailure bool `json:"stopOnFailure"`
+}
+
+type TestCase struct {
+	Input    string `json:"input"`
+	Expected string `json:"expected"`
+}
+
+// Comparison configures how output is compared with the expected output.
+// The zero value requires an exact match.
+type Comparison struct {
+	// TrimWhitespace ignores whitespace at the start and end of every line
+	// and blank lines at the end.
+	TrimWhitespace bool `json:"trimWhitespace"`
+	// IgnoreTrailingNewlines ignores newlines at the end of the output.
+	IgnoreTrailingNewlines bool `json:"ignoreTrailingNewlines"`
+	// FloatTolerance, when positive, compares whitespace separated tokens
+	// and accepts numbers within this absolute or relative difference.
+	FloatTolerance float64 `json:"floatTolerance"`
+}
+
+// Checker is a program judging the output of a test case, given like the
+// submission. It follows the testlib convention: it is run with the paths of
+// the input, the submission's output and the expected output as arguments,
+// and exits with 0 for accepted, 1 for a wrong answer, 2 for a presentation
+// error, 3 if it failed itself and 7 for a partial score, which it writes as
+// the first number of its message on stderr, e.g. "points 0.5".
+type Checker struct {
+	Language   string `json:"language"`
+	Code       string `json:"code"`
+	Files      []File `json:"files"`
+	Entrypoint string `json:"entrypoint"`
+}
+
+type TestResult struct {
+	Verdict  Verdict `json:"verdict"`
+	Stdout   string  `json:"stdout"`
+	Stderr   string  `json:"stderr"`
+	ExitCode int     `json:"exitCode"`
+	TimeMs   int64   `json:"timeMs"`
+	Usage    Usage   `json:"usage"`
+	// Score is between 0 and 1; only a checker gives partial scores.
+	Score          float64 `json:"score"`
+	CheckerMessage string  `json:"checkerMessage,omitempty"`
+}
+
+// JudgeResponse holds the overall verdict, which is the verdict of the
+// first test case that was not accepted, and the results of the test cases
+// that were run. Score is the sum of their scores. A compile error leaves
+// Tests empty.
+type JudgeResponse struct {
+	Verdict       Verdict      `json:"verdict"`
+	Passed        int          `json:"passed"`
+	Total         int          `json:"total"`
+	Score         float64      `json:"score"`
+	CompileOutput string       `json:"compileOutput,omitempty"`
+	Tests         []TestResult `json:"tests"`
+	Timings       Timings      `json:"timings"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// Language describes how code in one language is compiled and run. Commands
+// may reference the source file to run with {{file}}, its path relative to
+// the working directory without extension with {{name}} and the working
+// directory with {{dir}}.
+type Language struct {
+	Name    string `yaml:"-"`
+	Label   string `yaml:"label" validate:"required"`
+	Version string `yaml:"version" validate:"required"`
+	Image   string `yaml:"image" validate:"required"`
+	// FileName is the name the code is stored under.
+	FileName string `yaml:"fileName" validate:"required"`
+	// NamePatterns are tried in order against the code; the first capture
+	// group of the first match replaces the base name of FileName.
+	NamePatterns []string `yaml:"namePatterns"`
+	Compile      []string `yaml:"compile"`
+	Run          []string `yaml:"run" validate:"required,min=1"`
+	// Repl is the interactive command of sessions started without code;
+	// languages without one only run code in sessions.
+	Repl []string `yaml:"repl"`
+	// Timeout in seconds for running; zero uses the server default.
+	Timeout int `yaml:"timeout" validate:"gte=0"`
+	// CompileTimeout in seconds for compiling; zero uses Timeout.
+	CompileTimeout int            `yaml:"compileTimeout" validate:"gte=0"`
+	Limits         LanguageLimits `yaml:"limits"`
+	// Isolation is how runs are kept apart, see IsolationWorkdir and
+	// IsolationRecycle. Empty means IsolationWorkdir.
+	Isolation string `yaml:"isolation" validate:"omitempty,oneof=workdir recycle"`
+}
+
+const (
+	// IsolationWorkdir reuses the container but runs every job in a freshly
+	// emptied tmpfs working directory and kills all leftover processes. It
+	// needs a read-only root filesystem; with a writable one the executor
+	// falls back to IsolationRecycle.
+	IsolationWorkdir = "workdir"
+	// IsolationRecycle throws the container away after every job and
+	// replaces it in the background.
+	IsolationRecycle = "recycle"
+)
+
+// LanguageLimits override the default sandbox resources for a language;
+// zero values keep the defaults.
+type LanguageLimits struct {
+	CPUs     float64 `yaml:"cpus" validate:"omitempty,gt=0,lte=64"`
+	MemoryMB int     `yaml:"memoryMB" validate:"omitempty,min=6,max=65536"`
+}
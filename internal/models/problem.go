@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Problem is an entry of the problem catalog. Submissions are judged against
+// the sample tests followed by the hidden tests, which only admins can see.
+type Problem struct {
+	// ID is chosen by the admin and used in URLs, e.g. "two-sum".
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Statement is Markdown.
+	Statement     string `json:"statement"`
+	TimeLimitMs   int    `json:"timeLimitMs"`
+	MemoryLimitMB int    `json:"memoryLimitMb"`
+	// Languages allowed for submissions; empty allows all.
+	Languages   []string   `json:"languages"`
+	SampleTests []TestCase `json:"sampleTests"`
+	HiddenTests []TestCase `json:"hiddenTests"`
+	Comparison  Comparison `json:"comparison"`
+	Checker     *Checker   `json:"checker,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// ProblemSubmission is a solution submitted for a problem.
+type ProblemSubmission struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
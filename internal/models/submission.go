@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Submission is a finished execution or judgement in the submission
+// history. Status is set for executions and Verdict for judgements.
+type Submission struct {
+	ID       int64  `json:"id"`
+	User     string `json:"user,omitempty"`
+	Language string `json:"language"`
+	// ProblemID is set for solutions submitted to a problem.
+	ProblemID string `json:"problemId,omitempty"`
+	// CodeHash is the hex SHA-256 of the code, or of the paths and contents
+	// of all files for multi-file submissions, whose files are not kept.
+	CodeHash string          `json:"codeHash"`
+	Code     string          `json:"code"`
+	Status   ExecutionStatus `json:"status,omitempty"`
+	Verdict  Verdict         `json:"verdict,omitempty"`
+	ExitCode int             `json:"exitCode"`
+	// Stdout and Stderr are cut off after a limit, which sets Truncated.
+	Stdout      string    `json:"stdout"`
+	Stderr      string    `json:"stderr"`
+	Truncated   bool      `json:"truncated"`
+	Timings     Timings   `json:"timings"`
+	ContainerID string    `json:"containerId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// SubmissionPage is a page of the submission history, newest first.
+// NextBefore, if set, fetches the next page.
+type SubmissionPage struct {
+	Submissions []Submission `json:"submissions"`
+	NextBefore  int64        `json:"nextBefore,omitempty"`
+}
@@ -1,24 +1,113 @@
 package models
 
+import "time"
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// ExecuteRequest runs either Code, stored under the language's file name,
+// or the Entrypoint among Files. Files may also accompany Code, e.g. as
+// helper modules or data files.
 type ExecuteRequest struct {
-	Language string `json:"language"`
-	Code     string `json:"code"`
+	Language   string `json:"language"`
+	Code       string `json:"code"`
+	Stdin      string `json:"stdin"`
+	Files      []File `json:"files"`
+	Entrypoint string `json:"entrypoint"`
+}
+
+// File is a file of a multi-file execution. Path is relative to the working
+// directory.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Encoding is empty for plain text or "base64".
+	Encoding string `json:"encoding,omitempty"`
 }
 
+const FileEncodingBase64 = "base64"
+
+// SessionRequest starts an interactive session. Without code the session
+// runs the language's REPL.
+type SessionRequest struct {
+	Language   string `json:"language"`
+	Code       string `json:"code"`
+	Files      []File `json:"files"`
+	Entrypoint string `json:"entrypoint"`
+}
+
+type ExecutionStatus string
+
+const (
+	StatusOK           ExecutionStatus = "ok"
+	StatusRuntimeError ExecutionStatus = "runtime_error"
+	StatusCompileError ExecutionStatus = "compile_error"
+	StatusTimeout      ExecutionStatus = "timeout"
+	StatusKilled       ExecutionStatus = "killed"
+	// StatusOutputLimitExceeded means the program was killed for writing
+	// more than the output limit.
+	StatusOutputLimitExceeded ExecutionStatus = "output_limit_exceeded"
+)
+
+// ExecuteResponse describes a run. CompileOutput holds the diagnostics of
+// the compiler, if the language has one, also when compiling succeeded;
+// with StatusCompileError the code was not run.
 type ExecuteResponse struct {
-	Output string `json:"output"`
+	CompileOutput string          `json:"compileOutput,omitempty"`
+	Stdout        string          `json:"stdout"`
+	Stderr        string          `json:"stderr"`
+	ExitCode      int             `json:"exitCode"`
+	Status        ExecutionStatus `json:"status"`
+	// Truncated is set when output beyond the output limit was dropped.
+	Truncated bool    `json:"truncated"`
+	Usage     Usage   `json:"usage"`
+	Timings   Timings `json:"timings"`
+}
+
+// Usage is what a run of a program consumed. CPUMs counts user and system
+// time of all its processes and OOMKilled tells whether the kernel killed
+// one of them for running out of memory. PeakMemoryKB is UnknownMemory
+// when the sandbox cannot tell, e.g. because an earlier job in the same
+// sandbox used more memory.
+type Usage struct {
+	WallMs       int64 `json:"wallMs"`
+	CPUMs        int64 `json:"cpuMs"`
+	PeakMemoryKB int64 `json:"peakMemoryKb"`
+	OOMKilled    bool  `json:"oomKilled"`
 }
 
-var LanguageToExtension = map[string]string{
-	"python3": "py",
-	"nodejs":  "js",
+// UnknownMemory is the PeakMemoryKB of runs whose peak is unknown.
+const UnknownMemory = -1
+
+// Timings of an execution in milliseconds. WaitMs is the time spent waiting
+// for an idle sandbox.
+type Timings struct {
+	WaitMs    int64 `json:"waitMs"`
+	CompileMs int64 `json:"compileMs,omitempty"`
+	RunMs     int64 `json:"runMs"`
+	TotalMs   int64 `json:"totalMs"`
 }
 
-var LanguageToSandbox = map[string]string{
-	"python3": "sandbox-python",
-	"nodejs":  "sandbox-nodejs",
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job is an asynchronous execution. Result is set once the job completed;
+// Error describes why a failed job could not be run.
+type Job struct {
+	ID         string           `json:"id"`
+	Language   string           `json:"language"`
+	Status     JobStatus        `json:"status"`
+	Result     *ExecuteResponse `json:"result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	StartedAt  *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
 }
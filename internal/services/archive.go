@@ -0,0 +1,92 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// archiveFile is a regular file to be put into an archive. Name is a clean
+// relative path and a zero Mode means 0644.
+type archiveFile struct {
+	Name    string
+	Content []byte
+	Mode    int64
+}
+
+// tarFiles builds a tar archive holding the given regular files, preceded by
+// entries for their parent directories.
+func tarFiles(files []archiveFile) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if err := tarParentDirs(tw, dirs, path.Dir(file.Name)); err != nil {
+			return nil, err
+		}
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: file.Name, Mode: mode, Size: int64(len(file.Content))}); err != nil {
+			return nil, fmt.Errorf("failed to write archive header: %w", err)
+		}
+		if _, err := tw.Write(file.Content); err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close archive: %w", err)
+	}
+	return &buf, nil
+}
+
+// tarParentDirs writes entries for dir and its parents that are not in dirs yet.
+func tarParentDirs(tw *tar.Writer, dirs map[string]bool, dir string) error {
+	if dir == "." || dirs[dir] {
+		return nil
+	}
+	if err := tarParentDirs(tw, dirs, path.Dir(dir)); err != nil {
+		return err
+	}
+	dirs[dir] = true
+	if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		return fmt.Errorf("failed to write archive header: %w", err)
+	}
+	return nil
+}
+
+// untarFiles reads the regular files of an archive of a directory, as
+// produced by Runtime.CopyOut, and names them relative to that directory.
+func untarFiles(archive io.Reader) ([]archiveFile, error) {
+	var files []archiveFile
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Entries are named after the directory itself
+		_, name, ok := strings.Cut(strings.TrimPrefix(path.Clean(header.Name), "/"), "/")
+		if !ok {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		files = append(files, archiveFile{Name: name, Content: content, Mode: header.Mode & 0o777})
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// artifactExt is the file extension of cache entries, which are written to
+// files starting with artifactTmpPrefix first.
+const (
+	artifactExt       = ".gob"
+	artifactTmpPrefix = "tmp-"
+)
+
+// ArtifactCache keeps the files compilers produce on the host, so that
+// code compiled before is copied into the sandbox instead of compiled
+// again. Entries are addressed by everything that goes into a build: the
+// language, its version and compile command, the ID of the image compiling
+// it and the files of the submission. The least recently used entries are
+// evicted once the cache exceeds its size. It is safe for concurrent use.
+type ArtifactCache struct {
+	dir      string
+	maxBytes int64
+	logger   *log.Logger
+
+	mu      sync.Mutex
+	lru     *list.List // of *artifactEntry, most recently used first
+	entries map[string]*list.Element
+	size    int64
+}
+
+type artifactEntry struct {
+	key  string
+	size int64
+}
+
+// artifact is what a successful compilation left in the working directory
+// besides the submitted files, and the compiler's output.
+type artifact struct {
+	Files  []archiveFile
+	Output string
+}
+
+// NewArtifactCache opens the cache in dir, creating the directory if
+// needed. Entries of earlier runs are kept, in the order they were last used,
+// and files of interrupted writes are deleted.
+func NewArtifactCache(dir string, maxBytes int64, logger *log.Logger) (*ArtifactCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create artifact cache: %w", err)
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact cache: %w", err)
+	}
+
+	type existing struct {
+		entry   *artifactEntry
+		modTime time.Time
+	}
+	var found []existing
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(name, artifactTmpPrefix) {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				logger.Printf("Failed to remove unfinished artifact %s: %v", name, err)
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, artifactExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, existing{&artifactEntry{strings.TrimSuffix(name, artifactExt), info.Size()}, info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.After(found[j].modTime) })
+
+	c := &ArtifactCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		logger:   logger,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	for _, f := range found {
+		c.entries[f.entry.key] = c.lru.PushBack(f.entry)
+		c.size += f.entry.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+
+	logger.Printf("Artifact cache in %s holds %d builds (%d of %d bytes)", dir, c.lru.Len(), c.size, maxBytes)
+	return c, nil
+}
+
+// artifactKey addresses the build of files with the compile command of lang
+// in a container of the image imageID. Files are hashed in name order, so
+// their order in the request does not matter.
+func artifactKey(lang *language, imageID string, fileName string, files []archiveFile) string {
+	h := sha256.New()
+	field := func(s string) {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	field(lang.Name)
+	field(lang.Version)
+	field(lang.Image)
+	field(imageID)
+	field(strings.Join(lang.Compile, "\x00"))
+	field(fileName)
+
+	sorted := slices.Clone(files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, file := range sorted {
+		field(file.Name)
+		field(string(file.Content))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the artifact stored under key and marks it as recently used.
+func (c *ArtifactCache) get(key string) (artifact, bool) {
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(element)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return artifact{}, false
+	}
+
+	file := c.path(key)
+	data, err := os.ReadFile(file)
+	if err == nil {
+		var a artifact
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&a); err == nil {
+			// Keep the order of use for the next start
+			now := time.Now()
+			os.Chtimes(file, now, now)
+			return a, true
+		}
+	}
+
+	c.logger.Printf("Dropping unreadable artifact %s: %v", key[:12], err)
+	c.remove(key)
+	return artifact{}, false
+}
+
+// put stores an artifact under key and evicts the least recently used
+// entries until the cache fits its size again. Artifacts larger than the
+// whole cache are not stored.
+func (c *ArtifactCache) put(key string, a artifact) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a); err != nil {
+		return err
+	}
+	size := int64(buf.Len())
+	if size > c.maxBytes {
+		return nil
+	}
+
+	// Readers never see a partly written entry
+	tmp, err := os.CreateTemp(c.dir, artifactTmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.size -= element.Value.(*artifactEntry).size
+		c.lru.Remove(element)
+	}
+	c.entries[key] = c.lru.PushFront(&artifactEntry{key, size})
+	c.size += size
+	c.evict()
+	return nil
+}
+
+// remove deletes the entry under key.
+func (c *ArtifactCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.drop(element)
+	}
+}
+
+// evict drops least recently used entries while the cache is too large.
+// The caller must hold c.mu.
+func (c *ArtifactCache) evict() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		c.drop(c.lru.Back())
+	}
+}
+
+// drop deletes an entry and its file. The caller must hold c.mu.
+func (c *ArtifactCache) drop(element *list.Element) {
+	entry := c.lru.Remove(element).(*artifactEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	if err := os.Remove(c.path(entry.key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		c.logger.Printf("Failed to remove artifact %s: %v", entry.key[:12], err)
+	}
+}
+
+func (c *ArtifactCache) path(key string) string {
+	return filepath.Join(c.dir, key+artifactExt)
+}
+
+// buildArtifact collects the regular files of an archive of the working
+// directory that are not among the submitted files.
+func buildArtifact(archive io.Reader, files []archiveFile, output string) (artifact, error) {
+	submitted := make(map[string][]byte, len(files))
+	for _, file := range files {
+		submitted[file.Name] = file.Content
+	}
+
+	a := artifact{Output: output}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return a, nil
+		}
+		if err != nil {
+			return artifact{}, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Entries are named after the working directory itself
+		_, name, ok := strings.Cut(strings.TrimPrefix(path.Clean(header.Name), "/"), "/")
+		if !ok {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return artifact{}, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if original, ok := submitted[name]; ok && bytes.Equal(original, content) {
+			continue
+		}
+		a.Files = append(a.Files, archiveFile{Name: name, Content: content, Mode: header.Mode & 0o777})
+	}
+}
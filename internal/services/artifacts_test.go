@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func newTestArtifactCache(t *testing.T, dir string, maxBytes int64) *ArtifactCache {
+	t.Helper()
+	cache, err := NewArtifactCache(dir, maxBytes, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewArtifactCache: %v", err)
+	}
+	return cache
+}
+
+// testArtifact returns an artifact of one file of size bytes.
+func testArtifact(size int) artifact {
+	return artifact{Files: []archiveFile{{Name: "main", Content: make([]byte, size), Mode: 0o755}}}
+}
+
+// cached returns which of keys are in the cache, without using them.
+func cached(cache *ArtifactCache, keys ...string) []string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var found []string
+	for _, key := range keys {
+		if _, ok := cache.entries[key]; ok {
+			found = append(found, key)
+		}
+	}
+	return found
+}
+
+func TestArtifactCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+
+	// Measure an entry to fit two of them
+	probe := newTestArtifactCache(t, t.TempDir(), 1<<20)
+	if err := probe.put("probe", testArtifact(1000)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	cache := newTestArtifactCache(t, dir, 2*probe.size+probe.size/2)
+
+	// The mtimes keep the order of use across restarts
+	for _, key := range []string{"a", "b"} {
+		if err := cache.put(key, testArtifact(1000)); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("get a: not cached")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := cache.put("c", testArtifact(1000)); err != nil {
+		t.Fatalf("put c: %v", err)
+	}
+
+	if got := cached(cache, "a", "b", "c"); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Fatalf("cache holds %v, want a and c after evicting b", got)
+	}
+	if _, err := os.Stat(cache.path("b")); !os.IsNotExist(err) {
+		t.Errorf("file of evicted entry b: got %v, want it deleted", err)
+	}
+
+	reopened := newTestArtifactCache(t, dir, cache.maxBytes)
+	if got := cached(reopened, "a", "c"); len(got) != 2 {
+		t.Fatalf("reopened cache holds %v, want a and c", got)
+	}
+	if err := reopened.put("d", testArtifact(1000)); err != nil {
+		t.Fatalf("put d: %v", err)
+	}
+	if got := cached(reopened, "a", "c", "d"); len(got) != 2 || got[0] != "c" || got[1] != "d" {
+		t.Errorf("reopened cache holds %v, want c and d after evicting a", got)
+	}
+
+	got, ok := reopened.get("d")
+	if !ok || len(got.Files) != 1 || len(got.Files[0].Content) != 1000 || got.Files[0].Mode != 0o755 {
+		t.Errorf("get d: got %+v, %t, want the stored artifact", got, ok)
+	}
+}
+
+func TestArtifactCacheSkipsOversizedArtifacts(t *testing.T) {
+	cache := newTestArtifactCache(t, t.TempDir(), 100)
+	if err := cache.put("big", testArtifact(1000)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, ok := cache.get("big"); ok {
+		t.Error("an artifact larger than the cache was stored")
+	}
+}
+
+func TestNewArtifactCacheRemovesUnfinishedWrites(t *testing.T) {
+	dir := t.TempDir()
+	unfinished := filepath.Join(dir, artifactTmpPrefix+"123")
+	other := filepath.Join(dir, "README")
+	for _, name := range []string{unfinished, other} {
+		if err := os.WriteFile(name, []byte("partial"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := newTestArtifactCache(t, dir, 1<<20)
+	if _, err := os.Stat(unfinished); !os.IsNotExist(err) {
+		t.Errorf("unfinished write: got %v, want it deleted", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("unrelated file: %v", err)
+	}
+	if cache.size != 0 || cache.lru.Len() != 0 {
+		t.Errorf("cache counts %d entries of %d bytes, want none", cache.lru.Len(), cache.size)
+	}
+}
+
+func TestArtifactKey(t *testing.T) {
+	lang := newLanguage(testLanguages["cpp"])
+	a := archiveFile{Name: "main.cpp", Content: []byte("int main() {}")}
+	b := archiveFile{Name: "util.h", Content: []byte("#pragma once")}
+	key := artifactKey(lang, "sha256:1", "main.cpp", []archiveFile{a, b})
+
+	if got := artifactKey(lang, "sha256:1", "main.cpp", []archiveFile{b, a}); got != key {
+		t.Error("key depends on the order of the files")
+	}
+	if got := artifactKey(lang, "sha256:2", "main.cpp", []archiveFile{a, b}); got == key {
+		t.Error("key ignores the image ID")
+	}
+	changed := archiveFile{Name: "util.h", Content: []byte("#pragma twice")}
+	if got := artifactKey(lang, "sha256:1", "main.cpp", []archiveFile{a, changed}); got == key {
+		t.Error("key ignores the content of the files")
+	}
+}
+
+func TestExecuteUsesCachedBuild(t *testing.T) {
+	compiles := 0
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "g++" {
+			compiles++
+			io.WriteString(opts.Stderr, "warning\n")
+			c.Files[workDir+"/main"] = []byte("binary")
+			return ExecResult{}, nil
+		}
+		if _, ok := c.Files[workDir+"/main"]; !ok {
+			return ExecResult{ExitCode: 127}, nil
+		}
+		io.WriteString(opts.Stdout, "ran\n")
+		return ExecResult{}, nil
+	})
+	cache := newTestArtifactCache(t, t.TempDir(), 1<<20)
+	e := newTestExecutor(t, rt, ExecutorConfig{Artifacts: cache})
+
+	for i := range 2 {
+		response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "cpp", Code: "int main() {}"})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if response.Status != models.StatusOK || response.Stdout != "ran\n" || response.CompileOutput != "warning\n" {
+			t.Errorf("run %d: got status %s, stdout %q and compile output %q", i+1, response.Status, response.Stdout, response.CompileOutput)
+		}
+	}
+	if compiles != 1 {
+		t.Errorf("compiled %d times, want once", compiles)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// Paths of the files given to a checker, relative to the working directory.
+const (
+	checkerInput  = ".judge/input"
+	checkerOutput = ".judge/output"
+	checkerAnswer = ".judge/answer"
+)
+
+// Checker exit codes of the testlib convention. A partially correct answer,
+// testlib's _pc(n), exits with checkerPartial+n and scores n percent.
+const (
+	checkerAccepted          = 0
+	checkerWrongAnswer       = 1
+	checkerPresentationError = 2
+	checkerPoints            = 7
+	checkerPartial           = 50
+)
+
+var ErrCheckerFailed = errors.New("checker failed")
+
+// check runs a checker on the results of the test cases that were left
+// without a verdict and sets their verdicts and scores. With stopOnFailure
+// it stops at the first one that is not accepted.
+func (e *Executor) check(ctx context.Context, checker models.Checker, tests []models.TestCase, results []models.TestResult, stopOnFailure bool) error {
+	pending := false
+	for _, result := range results {
+		if result.Verdict == "" {
+			pending = true
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	pool, lang, err := e.prepare(checkerExecuteRequest(checker))
+	if err != nil {
+		return fmt.Errorf("checker: %w", err)
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, e.acquireTimeout)
+	lease, err := pool.Acquire(acquireCtx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to get container for checker from pool: %w", err)
+	}
+
+	timedOut, err := e.checkInContainer(ctx, lease.ContainerID, lang, checker, tests, results, stopOnFailure)
+	e.returnLease(lease, lang, leaseResponse(timedOut), err)
+	if err != nil {
+		e.logger.Printf("Checker failed in container %s: %v", lease.ContainerID[:12], err)
+		if errors.Is(err, ErrCheckerFailed) {
+			return err
+		}
+		return fmt.Errorf("checking failed: %w", err)
+	}
+	return nil
+}
+
+// checkInContainer compiles the checker in a leased container and runs it
+// once per unjudged test result. It also reports whether the checker timed
+// out, after which the container is not reused.
+func (e *Executor) checkInContainer(ctx context.Context, containerID string, lang *language, checker models.Checker, tests []models.TestCase, results []models.TestResult, stopOnFailure bool) (bool, error) {
+	files, fileName, err := e.bundle(lang, checkerExecuteRequest(checker))
+	if err != nil {
+		return false, err
+	}
+	if err := e.copyFilesToContainer(ctx, containerID, files); err != nil {
+		return false, fmt.Errorf("failed to copy checker to container: %w", err)
+	}
+
+	if len(lang.Compile) > 0 {
+		var output bytes.Buffer
+		status, _, err := e.compile(ctx, containerID, lang, fileName, files, &output)
+		if err != nil {
+			return false, err
+		}
+		if status != models.StatusOK {
+			return status == models.StatusTimeout, fmt.Errorf("%w to compile: %s", ErrCheckerFailed, firstLine(output.String()))
+		}
+	}
+
+	for i := range results {
+		if results[i].Verdict != "" {
+			continue
+		}
+
+		if err := e.copyFilesToContainer(ctx, containerID, []archiveFile{
+			{Name: checkerInput, Content: []byte(tests[i].Input)},
+			{Name: checkerOutput, Content: []byte(results[i].Stdout)},
+			{Name: checkerAnswer, Content: []byte(tests[i].Expected)},
+		}); err != nil {
+			return false, fmt.Errorf("failed to copy test case to container: %w", err)
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, e.runTimeout(lang))
+		var stderr bytes.Buffer
+		cmd := append(lang.command(lang.Run, fileName), checkerInput, checkerOutput, checkerAnswer)
+		result, err := e.runtime.Exec(runCtx, containerID, ExecOptions{Cmd: cmd, WorkDir: workDir, Stdout: io.Discard, Stderr: &stderr})
+		status, err := executionStatus(runCtx, result, err)
+		cancel()
+		if err != nil {
+			return false, fmt.Errorf("failed to run checker in container: %w", err)
+		}
+		if status == models.StatusTimeout {
+			return true, fmt.Errorf("%w on test case %d: timed out", ErrCheckerFailed, i+1)
+		}
+
+		message := strings.TrimSpace(stderr.String())
+		verdict, score, ok := checkerVerdict(result.ExitCode, message)
+		if !ok {
+			return false, fmt.Errorf("%w on test case %d with exit code %d: %s", ErrCheckerFailed, i+1, result.ExitCode, firstLine(message))
+		}
+		results[i].Verdict = verdict
+		results[i].Score = score
+		results[i].CheckerMessage = message
+
+		if stopOnFailure && verdict != models.VerdictAccepted {
+			break
+		}
+	}
+
+	return false, nil
+}
+
+// checkerVerdict maps the exit code of a checker to a verdict and score. It
+// fails for exit codes that do not judge the output, like testlib's 3 for a
+// failure of the checker itself.
+func checkerVerdict(exitCode int, message string) (models.Verdict, float64, bool) {
+	switch {
+	case exitCode == checkerAccepted:
+		return models.VerdictAccepted, 1, true
+	case exitCode == checkerWrongAnswer:
+		return models.VerdictWrongAnswer, 0, true
+	case exitCode == checkerPresentationError:
+		// Outputs are judged right or wrong, so a badly formatted one is
+		// simply wrong.
+		return models.VerdictWrongAnswer, 0, true
+	case exitCode == checkerPoints:
+		for _, field := range strings.Fields(message) {
+			if score, err := strconv.ParseFloat(field, 64); err == nil {
+				return scoreVerdict(score)
+			}
+		}
+		return "", 0, false
+	case exitCode >= checkerPartial && exitCode <= checkerPartial+100:
+		return scoreVerdict(float64(exitCode-checkerPartial) / 100)
+	default:
+		return "", 0, false
+	}
+}
+
+// scoreVerdict judges a test case given a score between 0 and 1.
+func scoreVerdict(score float64) (models.Verdict, float64, bool) {
+	switch {
+	case score >= 1:
+		return models.VerdictAccepted, 1, true
+	case score > 0:
+		return models.VerdictPartiallyAccepted, score, true
+	default:
+		return models.VerdictWrongAnswer, 0, true
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func checkerExecuteRequest(checker models.Checker) models.ExecuteRequest {
+	return models.ExecuteRequest{
+		Language:   checker.Language,
+		Code:       checker.Code,
+		Files:      checker.Files,
+		Entrypoint: checker.Entrypoint,
+	}
+}
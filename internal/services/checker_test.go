@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func TestCheckerVerdict(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		message  string
+		verdict  models.Verdict
+		score    float64
+		ok       bool
+	}{
+		{exitCode: 0, verdict: models.VerdictAccepted, score: 1, ok: true},
+		{exitCode: 1, message: "wrong answer", verdict: models.VerdictWrongAnswer, ok: true},
+		{exitCode: 2, message: "wrong output format", verdict: models.VerdictWrongAnswer, ok: true},
+		{exitCode: 3, message: "checker failed"},
+		{exitCode: 7, message: "points 0.25", verdict: models.VerdictPartiallyAccepted, score: 0.25, ok: true},
+		{exitCode: 7, message: "points 2", verdict: models.VerdictAccepted, score: 1, ok: true},
+		{exitCode: 7, message: "points 0", verdict: models.VerdictWrongAnswer, ok: true},
+		{exitCode: 7, message: "no points"},
+		{exitCode: 50, verdict: models.VerdictWrongAnswer, ok: true},
+		{exitCode: 80, verdict: models.VerdictPartiallyAccepted, score: 0.3, ok: true},
+		{exitCode: 150, verdict: models.VerdictAccepted, score: 1, ok: true},
+		{exitCode: 151},
+	}
+
+	for _, test := range tests {
+		verdict, score, ok := checkerVerdict(test.exitCode, test.message)
+		if verdict != test.verdict || score != test.score || ok != test.ok {
+			t.Errorf("exit code %d (%q): got %q, %v, %t, want %q, %v, %t", test.exitCode, test.message, verdict, score, ok, test.verdict, test.score, test.ok)
+		}
+	}
+}
+
+// checkerProgram runs submissions as an echo of their input and checkers as
+// a program exiting with the code the expected output starts with, followed
+// by the rest of it as its message.
+func checkerProgram(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+	if len(opts.Cmd) == 2 {
+		io.Copy(opts.Stdout, opts.Stdin)
+		return ExecResult{}, nil
+	}
+	answer := string(c.Files[workDir+"/"+opts.Cmd[4]])
+	code, message, _ := strings.Cut(answer, " ")
+	exitCode, err := strconv.Atoi(code)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	io.WriteString(opts.Stderr, message)
+	return ExecResult{ExitCode: exitCode}, nil
+}
+
+func TestJudgeWithChecker(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(checkerProgram), ExecutorConfig{})
+
+	req := models.JudgeRequest{
+		Language: "python3",
+		Code:     "solve()",
+		TestCases: []models.TestCase{
+			{Input: "a", Expected: "0 ok"},
+			{Input: "b", Expected: "7 points 0.5"},
+			{Input: "c", Expected: "75"},
+			{Input: "d", Expected: "2 wrong output format"},
+		},
+		Checker: &models.Checker{Language: "python3", Code: "check()"},
+	}
+	response, err := e.Judge(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+
+	want := []struct {
+		verdict models.Verdict
+		score   float64
+		message string
+	}{
+		{models.VerdictAccepted, 1, "ok"},
+		{models.VerdictPartiallyAccepted, 0.5, "points 0.5"},
+		{models.VerdictPartiallyAccepted, 0.25, ""},
+		{models.VerdictWrongAnswer, 0, "wrong output format"},
+	}
+	if len(response.Tests) != len(want) {
+		t.Fatalf("got %d test results, want %d", len(response.Tests), len(want))
+	}
+	for i, result := range response.Tests {
+		if result.Verdict != want[i].verdict || result.Score != want[i].score || result.CheckerMessage != want[i].message {
+			t.Errorf("test %d: got %s with score %v (%q), want %s with score %v (%q)", i+1, result.Verdict, result.Score, result.CheckerMessage, want[i].verdict, want[i].score, want[i].message)
+		}
+	}
+	if response.Score != 1.75 || response.Passed != 1 {
+		t.Errorf("got score %v with %d passed, want 1.75 with 1", response.Score, response.Passed)
+	}
+
+	req.TestCases = []models.TestCase{{Input: "a", Expected: "3 bad checker"}}
+	if _, err := e.Judge(context.Background(), req); !errors.Is(err, ErrCheckerFailed) {
+		t.Errorf("got error %v for a failing checker, want %v", err, ErrCheckerFailed)
+	}
+}
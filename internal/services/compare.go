@@ -0,0 +1,66 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// compareOutput reports whether the output of a program matches the
+// expected output under the given comparison. Line endings are always
+// normalized to \n.
+func compareOutput(got, want string, c models.Comparison) bool {
+	got, want = normalizeOutput(got, c), normalizeOutput(want, c)
+	if c.FloatTolerance <= 0 {
+		return got == want
+	}
+
+	gotTokens, wantTokens := strings.Fields(got), strings.Fields(want)
+	if len(gotTokens) != len(wantTokens) {
+		return false
+	}
+	for i := range gotTokens {
+		if !tokensMatch(gotTokens[i], wantTokens[i], c.FloatTolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeOutput(s string, c models.Comparison) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if c.TrimWhitespace {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(line)
+		}
+		s = strings.Join(lines, "\n")
+	}
+	if c.TrimWhitespace || c.IgnoreTrailingNewlines {
+		s = strings.TrimRight(s, "\n")
+	}
+	return s
+}
+
+// tokensMatch compares two tokens, as numbers within an absolute or
+// relative tolerance if both are numbers.
+func tokensMatch(got, want string, tolerance float64) bool {
+	if got == want {
+		return true
+	}
+	g, err := strconv.ParseFloat(got, 64)
+	if err != nil {
+		return false
+	}
+	w, err := strconv.ParseFloat(want, 64)
+	if err != nil {
+		return false
+	}
+	if math.IsNaN(g) || math.IsNaN(w) {
+		return false
+	}
+	diff := math.Abs(g - w)
+	return diff <= tolerance || diff <= tolerance*math.Abs(w)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func TestCompareOutput(t *testing.T) {
+	tests := []struct {
+		got, want  string
+		comparison models.Comparison
+		match      bool
+	}{
+		{"3\n", "3\n", models.Comparison{}, true},
+		{"3\r\n", "3\n", models.Comparison{}, true},
+		{"3", "3\n", models.Comparison{}, false},
+		{"3 \n", "3\n", models.Comparison{}, false},
+		{"3\n\n", "3", models.Comparison{IgnoreTrailingNewlines: true}, true},
+		{" 3 \n", "3\n", models.Comparison{IgnoreTrailingNewlines: true}, false},
+		{"  1 2 \n3\n\n", "1 2\n3", models.Comparison{TrimWhitespace: true}, true},
+		{"1 2\n", "1  2\n", models.Comparison{TrimWhitespace: true}, false},
+		{"0.3333\n", "0.33333333", models.Comparison{FloatTolerance: 1e-3}, true},
+		{"0.33\n", "0.33333333", models.Comparison{FloatTolerance: 1e-3}, false},
+		{"1000001", "1000000", models.Comparison{FloatTolerance: 1e-6}, true},
+		{"1 x", "1 x", models.Comparison{FloatTolerance: 1e-6}, true},
+		{"1", "1 2", models.Comparison{FloatTolerance: 1e-6}, false},
+		{"NaN", "NaN", models.Comparison{FloatTolerance: 1e-6}, true},
+		{"NaN", "nan", models.Comparison{FloatTolerance: 1e-6}, false},
+	}
+
+	for _, test := range tests {
+		if got := compareOutput(test.got, test.want, test.comparison); got != test.match {
+			t.Errorf("compareOutput(%q, %q, %+v) = %t, want %t", test.got, test.want, test.comparison, got, test.match)
+		}
+	}
+}
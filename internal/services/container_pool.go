@@ -1,52 +1,313 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os/exec"
-	"strings"
 	"sync"
+	"time"
 )
 
+// Bounds of the delay between failed container creations.
+const (
+	minCreateBackoff = time.Second
+	maxCreateBackoff = time.Minute
+)
+
+// DefaultHealthCheckInterval is used when PoolConfig.HealthCheckInterval is not set.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// PoolConfig controls the lifetime of pooled containers. Zero MaxAge and
+// MaxExecutions mean unlimited.
+type PoolConfig struct {
+	HealthCheckInterval time.Duration
+	MaxAge              time.Duration
+	MaxExecutions       int
+}
+
+// clock is the source of time of a pool. Tests replace it to drive the
+// supervisor without waiting.
+type clock struct {
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+var systemClock = clock{now: time.Now, after: time.After}
+
 type ContainerPool struct {
-	containers    chan string
-	allContainers []string // Track all created containers
-	language      string
-	image         string
-	maxSize       int
-	logger        *log.Logger
-	mu            sync.Mutex
-	shutdown      bool
-}
-
-func (e *Executor) initializePool(pool *ContainerPool) {
-	for i := 0; i < pool.maxSize; i++ {
-		containerID, err := e.createContainer(pool.image)
-		if err != nil {
-			pool.logger.Printf("Failed to create container %d/%d for %s: %v", i+1, pool.maxSize, pool.language, err)
+	containers chan string
+	all        map[string]*pooledContainer // Track all live containers, idle or in use
+	language   string
+	image      string
+	cpus       float64
+	memoryMB   int
+	maxSize    int
+	config     PoolConfig
+	clock      clock
+	runtime    Runtime
+	logger     *log.Logger
+	mu         sync.Mutex
+	shutdown   bool
+	wake       chan struct{} // Signals the supervisor that a container was retired
+	done       chan struct{} // Closed on shutdown
+}
+
+// Lease is exclusive use of a pooled container. It ends with exactly one
+// call to Release or Discard; later calls are no-ops.
+type Lease struct {
+	ContainerID string
+	pool        *ContainerPool
+	once        sync.Once
+}
+
+type pooledContainer struct {
+	created    time.Time
+	executions int
+}
+
+func newContainerPool(language string, image string, cpus float64, memoryMB int, maxSize int, config PoolConfig, runtime Runtime, logger *log.Logger) *ContainerPool {
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+
+	return &ContainerPool{
+		containers: make(chan string, maxSize),
+		all:        make(map[string]*pooledContainer),
+		language:   language,
+		image:      image,
+		cpus:       cpus,
+		memoryMB:   memoryMB,
+		maxSize:    maxSize,
+		config:     config,
+		clock:      systemClock,
+		runtime:    runtime,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+// superviseContainerPool keeps the pool filled until shutdown: it creates
+// missing containers, backing off while creation fails, and periodically
+// health-checks idle containers.
+func (e *Executor) superviseContainerPool(pool *ContainerPool) {
+	healthCheck := pool.clock.after(pool.config.HealthCheckInterval)
+	backoff := minCreateBackoff
+	filled := false
+	for {
+		if missing := pool.missing(); missing > 0 {
+			if err := e.addContainer(pool); err != nil {
+				pool.logger.Printf("Failed to create container for %s (%d missing), retrying in %s: %v", pool.language, missing, backoff, err)
+				select {
+				case <-pool.done:
+					return
+				case <-pool.clock.after(backoff):
+				}
+				backoff = min(backoff*2, maxCreateBackoff)
+			} else {
+				backoff = minCreateBackoff
+			}
 			continue
 		}
 
-		// Track the container in our list
-		pool.mu.Lock()
-		pool.allContainers = append(pool.allContainers, containerID)
-		pool.mu.Unlock()
+		if !filled {
+			filled = true
+			pool.logger.Printf("Container pool for %s fully initialized with %d containers", pool.language, pool.maxSize)
+		}
 
-		pool.logger.Printf("Created container %s for %s (%d/%d)", containerID[:12], pool.language, i+1, pool.maxSize)
-		pool.containers <- containerID
+		select {
+		case <-pool.done:
+			return
+		case <-pool.wake:
+		case <-healthCheck:
+			e.checkIdleContainers(pool)
+			healthCheck = pool.clock.after(pool.config.HealthCheckInterval)
+		}
 	}
-	pool.logger.Printf("Container pool for %s fully initialized with %d containers", pool.language, len(pool.allContainers))
 }
 
-func (e *Executor) createContainer(image string) (string, error) {
-	cmd := exec.Command("docker", "run", "-d", "--net=none", "--cpus=0.5", "--memory=50m", "--entrypoint=", image, "sleep", "3600")
-	output, err := cmd.Output()
+// addContainer creates a container and makes it available in the pool.
+func (e *Executor) addContainer(pool *ContainerPool) error {
+	containerID, err := e.createContainer(pool)
 	if err != nil {
-		return "", fmt.Errorf("failed to create container: %w", err)
+		return err
 	}
 
-	containerID := strings.TrimSpace(string(output))
-	return containerID, nil
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.shutdown {
+		go pool.stopAndRemoveContainer(containerID)
+		return nil
+	}
+
+	pool.all[containerID] = &pooledContainer{created: pool.clock.now()}
+	pool.containers <- containerID
+	pool.logger.Printf("Created container %s for %s (%d/%d)", containerID[:12], pool.language, len(pool.all), pool.maxSize)
+	return nil
+}
+
+func (e *Executor) createContainer(pool *ContainerPool) (string, error) {
+	spec := ContainerSpec{
+		Image:           pool.image,
+		Cmd:             []string{"sleep", "infinity"},
+		CPUs:            pool.cpus,
+		MemoryMB:        pool.memoryMB,
+		NetworkDisabled: true,
+		Labels:          e.containerLabels(pool.language),
+	}
+	e.sandbox.apply(&spec)
+	return e.runtime.Create(context.Background(), spec)
+}
+
+// checkIdleContainers retires idle containers that are no longer running or
+// have reached their maximum age. Containers in use are checked when they
+// are returned. Idle containers are taken out of the pool one at a time, so
+// jobs can still acquire the others while one is being inspected.
+func (e *Executor) checkIdleContainers(pool *ContainerPool) {
+	for range len(pool.containers) {
+		var containerID string
+		select {
+		case id, ok := <-pool.containers:
+			if !ok {
+				return
+			}
+			containerID = id
+		default:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		info, err := e.runtime.Inspect(ctx, containerID)
+		cancel()
+
+		switch {
+		case err != nil:
+			pool.retire(containerID, "health check failed: "+err.Error())
+		case !info.Running:
+			pool.retire(containerID, "container is not running")
+		case pool.expired(containerID):
+			pool.retire(containerID, "maximum age reached")
+		default:
+			pool.release(containerID)
+		}
+	}
+}
+
+// Acquire waits until a container is idle and leases it. It fails with
+// ErrPoolExhausted if ctx expires first.
+func (pool *ContainerPool) Acquire(ctx context.Context) (*Lease, error) {
+	if pool.IsShutdown() {
+		return nil, fmt.Errorf("container pool %w", ErrShuttingDown)
+	}
+
+	select {
+	case containerID, ok := <-pool.containers:
+		if !ok {
+			return nil, fmt.Errorf("container pool %w", ErrShuttingDown)
+		}
+		pool.logger.Printf("Acquired container %s for %s execution", containerID[:12], pool.language)
+		return &Lease{ContainerID: containerID, pool: pool}, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrPoolExhausted
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Release hands the container back for the next lease, unless it has
+// reached its execution limit or maximum age, in which case it is replaced.
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		if l.pool.used(l.ContainerID) {
+			l.pool.retire(l.ContainerID, "execution limit or maximum age reached")
+			return
+		}
+		l.pool.release(l.ContainerID)
+		l.pool.logger.Printf("Returned container %s to %s pool", l.ContainerID[:12], l.pool.language)
+	})
+}
+
+// Discard removes the container, e.g. because it timed out or may have been
+// tainted, and lets the supervisor replace it.
+func (l *Lease) Discard(reason string) {
+	l.once.Do(func() {
+		l.pool.retire(l.ContainerID, reason)
+	})
+}
+
+// missing returns how many containers the pool is short of.
+func (pool *ContainerPool) missing() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.maxSize - len(pool.all)
+}
+
+// expired reports whether a container has outlived the maximum age.
+func (pool *ContainerPool) expired(containerID string) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	c, ok := pool.all[containerID]
+	return ok && pool.config.MaxAge > 0 && pool.clock.now().Sub(c.created) > pool.config.MaxAge
+}
+
+// used records a finished execution and reports whether the container should
+// be retired because of its execution count or age.
+func (pool *ContainerPool) used(containerID string) bool {
+	pool.mu.Lock()
+	c, ok := pool.all[containerID]
+	if ok {
+		c.executions++
+	}
+	pool.mu.Unlock()
+
+	if !ok {
+		return true
+	}
+	if pool.config.MaxExecutions > 0 && c.executions >= pool.config.MaxExecutions {
+		return true
+	}
+	return pool.expired(containerID)
+}
+
+// retire removes a container from the pool in the background and asks the
+// supervisor for a replacement.
+func (pool *ContainerPool) retire(containerID string, reason string) {
+	pool.mu.Lock()
+	if pool.shutdown {
+		// CleanupPool removes every container still tracked
+		pool.mu.Unlock()
+		return
+	}
+	delete(pool.all, containerID)
+	pool.mu.Unlock()
+
+	pool.logger.Printf("Retiring container %s of %s pool: %s", containerID[:12], pool.language, reason)
+
+	go func() {
+		if err := pool.stopAndRemoveContainer(containerID); err != nil {
+			pool.logger.Printf("Failed to remove retired container %s: %v", containerID[:12], err)
+		}
+	}()
+
+	select {
+	case pool.wake <- struct{}{}:
+	default:
+	}
+}
+
+// release hands a container back to the pool. After shutdown the container
+// is left for CleanupPool, which removes every container still tracked.
+func (pool *ContainerPool) release(containerID string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.shutdown {
+		return
+	}
+	pool.containers <- containerID
 }
 
 // CleanupPool stops and removes all containers in the pool
@@ -58,6 +319,7 @@ func (pool *ContainerPool) CleanupPool() {
 		return
 	}
 	pool.shutdown = true
+	close(pool.done)
 
 	pool.logger.Printf("Starting cleanup for %s container pool...", pool.language)
 
@@ -69,10 +331,10 @@ func (pool *ContainerPool) CleanupPool() {
 		<-pool.containers
 	}
 
-	// Stop and remove ALL created containers (not just those in channel)
-	pool.logger.Printf("Cleaning up %d containers for %s", len(pool.allContainers), pool.language)
+	// Stop and remove ALL tracked containers (not just those in channel)
+	pool.logger.Printf("Cleaning up %d containers for %s", len(pool.all), pool.language)
 
-	for _, containerID := range pool.allContainers {
+	for containerID := range pool.all {
 		if err := pool.stopAndRemoveContainer(containerID); err != nil {
 			pool.logger.Printf("Failed to cleanup container %s: %v", containerID[:12], err)
 		} else {
@@ -80,29 +342,20 @@ func (pool *ContainerPool) CleanupPool() {
 		}
 	}
 
-	pool.logger.Printf("Cleanup completed for %s container pool (%d containers)", pool.language, len(pool.allContainers))
+	pool.logger.Printf("Cleanup completed for %s container pool (%d containers)", pool.language, len(pool.all))
 }
 
 // stopAndRemoveContainer stops and removes a specific container
 func (pool *ContainerPool) stopAndRemoveContainer(containerID string) error {
+	ctx := context.Background()
+
 	// Stop the container (force stop after 10 seconds)
-	stopCmd := exec.Command("docker", "stop", "-t", "10", containerID)
-	if err := stopCmd.Run(); err != nil {
+	if err := pool.runtime.Stop(ctx, containerID, 10*time.Second); err != nil {
 		pool.logger.Printf("Failed to stop container %s: %v", containerID[:12], err)
-		// Try to force kill if stop fails
-		killCmd := exec.Command("docker", "kill", containerID)
-		if killErr := killCmd.Run(); killErr != nil {
-			pool.logger.Printf("Failed to kill container %s: %v", containerID[:12], killErr)
-		}
 	}
 
-	// Remove the container
-	removeCmd := exec.Command("docker", "rm", "-f", containerID)
-	if err := removeCmd.Run(); err != nil {
-		return fmt.Errorf("failed to remove container %s: %w", containerID[:12], err)
-	}
-
-	return nil
+	// Remove the container, killing it if the stop failed
+	return pool.runtime.Remove(ctx, containerID)
 }
 
 // IsShutdown returns whether the pool is in shutdown state
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// fakeClock is a clock that only moves when the test advances it.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	d  time.Duration
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) clock() clock {
+	return clock{now: c.Now, after: c.After}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), d: d, ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d and fires the timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// Waiting reports whether a timer of duration d is pending.
+func (c *fakeClock) Waiting(d time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.ContainsFunc(c.timers, func(timer fakeTimer) bool { return timer.d == d })
+}
+
+// failingRuntime fails the first creations it is asked for.
+type failingRuntime struct {
+	*FakeRuntime
+	failures atomic.Int32
+}
+
+func (f *failingRuntime) Create(ctx context.Context, spec ContainerSpec) (string, error) {
+	if f.failures.Add(-1) >= 0 {
+		return "", errors.New("image not found")
+	}
+	return f.FakeRuntime.Create(ctx, spec)
+}
+
+// startTestPool starts supervising a pool of python3 containers, whose time
+// is driven by the returned clock.
+func startTestPool(t *testing.T, rt Runtime, size int, config PoolConfig) (*ContainerPool, *fakeClock) {
+	t.Helper()
+	logger := log.New(io.Discard, "", 0)
+	pool := newContainerPool("python3", "sandbox-python", DefaultCPUs, DefaultMemoryMB, size, config, rt, logger)
+	fc := newFakeClock()
+	pool.clock = fc.clock()
+
+	e := &Executor{runtime: rt, logger: logger}
+	go e.superviseContainerPool(pool)
+	t.Cleanup(pool.CleanupPool)
+	return pool, fc
+}
+
+// idle returns the IDs of the idle containers of a pool, leaving them in it.
+// A container the supervisor is health-checking is not idle.
+func idle(pool *ContainerPool) []string {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var ids []string
+	for range len(pool.containers) {
+		select {
+		case id := <-pool.containers:
+			ids = append(ids, id)
+		default:
+		}
+	}
+	for _, id := range ids {
+		pool.containers <- id
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestAcquireLeasesEachContainerOnce(t *testing.T) {
+	e := newTestExecutor(t, NewFakeRuntime(), ExecutorConfig{MaxConcurrent: 2})
+	pool := e.pools["python3"]
+
+	// An expired context only gets a container that is idle right away
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	second, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if first.ContainerID == second.ContainerID {
+		t.Fatalf("both leases got container %s", first.ContainerID)
+	}
+	if _, err := pool.Acquire(expired); !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("Acquire of a busy pool: got %v, want ErrPoolExhausted", err)
+	}
+
+	// Ending a lease more than once hands the container back only once
+	first.Release()
+	first.Release()
+	first.Discard("ended twice")
+
+	third, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if third.ContainerID != first.ContainerID {
+		t.Errorf("got container %s, want the released %s", third.ContainerID, first.ContainerID)
+	}
+	if lease, err := pool.Acquire(expired); err == nil {
+		t.Errorf("container %s was leased twice", lease.ContainerID)
+	}
+}
+
+func TestConcurrentExecutionsNeverShareContainers(t *testing.T) {
+	var mu sync.Mutex
+	busy := make(map[string]bool)
+	shared := false
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		mu.Lock()
+		shared = shared || busy[c.ID]
+		busy[c.ID] = true
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		busy[c.ID] = false
+		mu.Unlock()
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{MaxConcurrent: 2})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "run()"}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Execute: %v", err)
+	}
+	if shared {
+		t.Error("a container ran two executions at once")
+	}
+}
+
+func TestSupervisorRetriesFailedCreations(t *testing.T) {
+	rt := &failingRuntime{FakeRuntime: NewFakeRuntime()}
+	rt.failures.Store(2)
+	pool, fc := startTestPool(t, rt, 1, PoolConfig{})
+
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		waitFor(t, "a retry after "+backoff.String(), func() bool { return fc.Waiting(backoff) })
+		if ids := idle(pool); len(ids) != 0 {
+			t.Fatalf("pool has containers %v while creation fails", ids)
+		}
+		fc.Advance(backoff)
+	}
+	waitFor(t, "the pool to fill", func() bool { return len(idle(pool)) == 1 })
+}
+
+func TestSupervisorReplacesUnhealthyContainers(t *testing.T) {
+	rt := NewFakeRuntime()
+	pool, fc := startTestPool(t, rt, 2, PoolConfig{HealthCheckInterval: time.Minute})
+	waitFor(t, "the pool to fill", func() bool { return len(idle(pool)) == 2 })
+
+	containers := idle(pool)
+	dead := containers[0]
+	rt.Stop(context.Background(), dead, 0)
+	fc.Advance(time.Minute)
+
+	waitFor(t, "the dead container to be replaced", func() bool {
+		ids := idle(pool)
+		return len(ids) == 2 && !slices.Contains(ids, dead)
+	})
+	if !slices.Contains(idle(pool), containers[1]) {
+		t.Errorf("healthy container %s was replaced", containers[1])
+	}
+	waitFor(t, "removal of the dead container", func() bool { return rt.Container(dead) == nil })
+}
+
+func TestSupervisorRetiresOldContainers(t *testing.T) {
+	rt := NewFakeRuntime()
+	pool, fc := startTestPool(t, rt, 1, PoolConfig{HealthCheckInterval: time.Minute, MaxAge: time.Hour})
+	waitFor(t, "the pool to fill", func() bool { return len(idle(pool)) == 1 })
+	old := idle(pool)[0]
+
+	fc.Advance(time.Minute)
+	waitFor(t, "the next health check", func() bool { return fc.Waiting(time.Minute) })
+	if ids := idle(pool); !slices.Equal(ids, []string{old}) {
+		t.Fatalf("got containers %v, want %s kept until its maximum age", ids, old)
+	}
+
+	fc.Advance(time.Hour)
+	waitFor(t, "the old container to be replaced", func() bool {
+		ids := idle(pool)
+		return len(ids) == 1 && ids[0] != old
+	})
+}
+
+func TestExecuteRetiresUsedUpContainer(t *testing.T) {
+	var seen []string
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		seen = append(seen, c.ID)
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{Pool: PoolConfig{MaxExecutions: 2}})
+
+	for range 3 {
+		if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	if len(seen) != 3 || seen[0] != seen[1] || seen[2] == seen[1] {
+		t.Errorf("runs used containers %v, want one for two runs and a new one for the third", seen)
+	}
+	waitFor(t, "removal of the used up container", func() bool { return rt.Container(seen[0]) == nil })
+}
@@ -1,97 +1,306 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"ikurotime/code-engine/internal/models"
 )
 
+// exitCodeSIGKILL is the exit code of a process killed with SIGKILL, e.g. by
+// the OOM killer.
+const exitCodeSIGKILL = 128 + 9
+
+// DefaultMaxStdinBytes is used when ExecutorConfig.MaxStdinBytes is not set.
+const DefaultMaxStdinBytes = 1 << 20
+
+// DefaultMaxOutputBytes is used when ExecutorConfig.MaxOutputBytes is not set.
+const DefaultMaxOutputBytes = 1 << 20
+
+// DefaultAcquireTimeout is used when ExecutorConfig.AcquireTimeout is not set.
+const DefaultAcquireTimeout = 5 * time.Second
+
+// Sandbox limits used when ExecutorConfig leaves them unset.
+const (
+	DefaultCPUs     = 0.5
+	DefaultMemoryMB = 50
+)
+
+// MinMemoryMB is the smallest memory limit the container engine accepts.
+const MinMemoryMB = 6
+
+var (
+	ErrShuttingDown        = errors.New("is shutting down")
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrPoolExhausted       = errors.New("no containers available, pool exhausted")
+	ErrStdinTooLarge       = errors.New("stdin exceeds the size limit")
+)
+
+type ExecutorConfig struct {
+	Languages     map[string]models.Language
+	MaxConcurrent int
+	Timeout       time.Duration
+	MaxStdinBytes int
+	// MaxOutputBytes limits stdout and stderr of a run together; programs
+	// writing more are killed. Compile output is only cut.
+	MaxOutputBytes int
+	// MaxBundleBytes limits the code and files of an execution together.
+	MaxBundleBytes int
+	// AcquireTimeout bounds the wait for an idle container.
+	AcquireTimeout time.Duration
+	// CPUs and MemoryMB limit sandboxes of languages without their own limits.
+	CPUs     float64
+	MemoryMB int
+	Pool     PoolConfig
+	// Instance names this server in container labels. It must be unique
+	// among servers sharing a container engine and stable across restarts;
+	// it defaults to the host name.
+	Instance  string
+	Reconcile ReconcileMode
+	Sessions  SessionConfig
+	Sandbox   SandboxConfig
+	// Submissions, if set, records every finished execution and judgement.
+	Submissions SubmissionRecorder
+	// Artifacts, if set, caches the output of compilers.
+	Artifacts *ArtifactCache
+}
+
 type Executor struct {
-	pools    map[string]*ContainerPool
-	timeout  time.Duration
-	logger   *log.Logger
-	mu       sync.RWMutex
-	shutdown bool
+	runtime        Runtime
+	languages      map[string]*language
+	pools          map[string]*ContainerPool
+	timeout        time.Duration
+	acquireTimeout time.Duration
+	maxStdinBytes  int
+	maxOutputBytes int
+	maxBundleBytes int
+	instance       string
+	sessions       SessionConfig
+	sessionSlots   chan struct{} // Holds a value per running session
+	sandbox        SandboxConfig
+	submissions    SubmissionRecorder
+	artifacts      *ArtifactCache
+	logger         *log.Logger
+	mu             sync.RWMutex
+	shutdown       bool
 }
 
-func NewExecutor(maxConcurrent int, timeout time.Duration, logger *log.Logger) *Executor {
+func NewExecutor(runtime Runtime, cfg ExecutorConfig, logger *log.Logger) *Executor {
 	executor := &Executor{
-		pools:   make(map[string]*ContainerPool),
-		timeout: timeout,
-		logger:  logger,
-	}
-
-	for language, image := range models.LanguageToSandbox {
-		pool := &ContainerPool{
-			containers: make(chan string, maxConcurrent),
-			language:   language,
-			image:      image,
-			maxSize:    maxConcurrent,
-			logger:     logger,
+		runtime:        runtime,
+		languages:      make(map[string]*language),
+		pools:          make(map[string]*ContainerPool),
+		timeout:        cfg.Timeout,
+		acquireTimeout: cfg.AcquireTimeout,
+		maxStdinBytes:  cfg.MaxStdinBytes,
+		maxOutputBytes: cfg.MaxOutputBytes,
+		maxBundleBytes: cfg.MaxBundleBytes,
+		instance:       cfg.Instance,
+		sessions:       cfg.Sessions,
+		sandbox:        cfg.Sandbox,
+		submissions:    cfg.Submissions,
+		artifacts:      cfg.Artifacts,
+		logger:         logger,
+	}
+	if executor.maxStdinBytes <= 0 {
+		executor.maxStdinBytes = DefaultMaxStdinBytes
+	}
+	if executor.maxOutputBytes <= 0 {
+		executor.maxOutputBytes = DefaultMaxOutputBytes
+	}
+	if executor.maxBundleBytes <= 0 {
+		executor.maxBundleBytes = DefaultMaxBundleBytes
+	}
+	if executor.acquireTimeout <= 0 {
+		executor.acquireTimeout = DefaultAcquireTimeout
+	}
+	if executor.sessions.IdleTimeout <= 0 {
+		executor.sessions.IdleTimeout = DefaultSessionIdleTimeout
+	}
+	if executor.sessions.MaxDuration <= 0 {
+		executor.sessions.MaxDuration = DefaultSessionMaxDuration
+	}
+	if executor.sessions.MaxConcurrent <= 0 {
+		executor.sessions.MaxConcurrent = max(cfg.MaxConcurrent/2, 1)
+	}
+	executor.sessionSlots = make(chan struct{}, executor.sessions.MaxConcurrent)
+	if executor.instance == "" {
+		executor.instance, _ = os.Hostname()
+	}
+
+	if cfg.CPUs <= 0 {
+		cfg.CPUs = DefaultCPUs
+	}
+	if cfg.MemoryMB <= 0 {
+		cfg.MemoryMB = DefaultMemoryMB
+	}
+
+	// Leftovers must be gone before the pools create containers with the same labels
+	executor.reconcileOrphans(cfg.Reconcile)
+
+	for name, lang := range cfg.Languages {
+		if lang.Isolation != models.IsolationRecycle && !executor.sandbox.readOnly() {
+			// On a writable root filesystem a job could leave files anywhere,
+			// even replace the shell that resets the container
+			executor.logger.Printf("Recycling %s containers after every job: workdir isolation needs a read-only root filesystem", name)
+			lang.Isolation = models.IsolationRecycle
 		}
-		executor.pools[language] = pool
+		executor.languages[name] = newLanguage(lang)
 
-		executor.logger.Printf("Initializing container pool for %s (image: %s, size: %d)", language, image, maxConcurrent)
+		cpus := lang.Limits.CPUs
+		if cpus == 0 {
+			cpus = cfg.CPUs
+		}
+		memoryMB := lang.Limits.MemoryMB
+		if memoryMB == 0 {
+			memoryMB = cfg.MemoryMB
+		}
+
+		pool := newContainerPool(name, lang.Image, cpus, memoryMB, cfg.MaxConcurrent, cfg.Pool, runtime, logger)
+		executor.pools[name] = pool
 
-		go executor.initializePool(pool)
+		executor.logger.Printf("Initializing container pool for %s %s (image: %s, size: %d, cpus: %g, memory: %dMB)", name, lang.Version, lang.Image, cfg.MaxConcurrent, cpus, memoryMB)
+
+		go executor.superviseContainerPool(pool)
 	}
 
 	return executor
 }
 
-func (e *Executor) Execute(req models.ExecuteRequest) (string, error) {
+// Execute runs the code and reports how it went. Errors are only returned
+// when the code could not be run at all; compile errors, crashes and
+// timeouts are described by the response status. Execute gives up with
+// ErrPoolExhausted if no container becomes idle within the acquire timeout.
+func (e *Executor) Execute(ctx context.Context, req models.ExecuteRequest) (models.ExecuteResponse, error) {
+	return e.execute(ctx, req, e.acquireTimeout, nil)
+}
+
+// OutputFunc receives the output of a running program as it is produced
+// on the "stdout" and "stderr" streams, and before that the compiler's
+// output on the "compile" stream. Calls are never concurrent and data must
+// not be retained after the call returns.
+type OutputFunc func(stream string, data []byte)
+
+// ExecuteStream runs the code like Execute and additionally passes its
+// output to onOutput while it runs.
+func (e *Executor) ExecuteStream(ctx context.Context, req models.ExecuteRequest, onOutput OutputFunc) (models.ExecuteResponse, error) {
+	return e.execute(ctx, req, e.acquireTimeout, onOutput)
+}
+
+// Validate checks that a request can be executed, without running it.
+func (e *Executor) Validate(req models.ExecuteRequest) error {
+	_, _, err := e.prepare(req)
+	return err
+}
+
+// prepare returns the pool and language of a request to execute after
+// checking it.
+func (e *Executor) prepare(req models.ExecuteRequest) (*ContainerPool, *language, error) {
+	if req.Code == "" && req.Entrypoint == "" {
+		return nil, nil, fmt.Errorf("%w: code or entrypoint is required", ErrInvalidFiles)
+	}
+	return e.lookup(req)
+}
+
+// lookup returns the pool and language of a request after checking it.
+func (e *Executor) lookup(req models.ExecuteRequest) (*ContainerPool, *language, error) {
 	e.mu.RLock()
 	if e.shutdown {
 		e.mu.RUnlock()
-		return "", fmt.Errorf("executor is shutting down")
+		return nil, nil, fmt.Errorf("executor %w", ErrShuttingDown)
 	}
 	pool, exists := e.pools[req.Language]
+	lang := e.languages[req.Language]
 	e.mu.RUnlock()
 
 	if !exists {
-		return "", fmt.Errorf("unsupported language: %s", req.Language)
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, req.Language)
+	}
+
+	if len(req.Stdin) > e.maxStdinBytes {
+		return nil, nil, fmt.Errorf("%w of %d bytes", ErrStdinTooLarge, e.maxStdinBytes)
+	}
+
+	if _, _, err := e.bundle(lang, req); err != nil {
+		return nil, nil, err
 	}
 
 	if pool.IsShutdown() {
-		return "", fmt.Errorf("container pool for %s is shutting down", req.Language)
+		return nil, nil, fmt.Errorf("container pool for %s %w", req.Language, ErrShuttingDown)
 	}
 
-	e.logger.Printf("Executing %s code, waiting for container from pool...", req.Language)
+	return pool, lang, nil
+}
 
-	containerID, err := getContainerFromPool(pool, req.Language)
+// execute runs the code like Execute. A zero acquireTimeout waits for an
+// idle container for as long as ctx allows.
+func (e *Executor) execute(ctx context.Context, req models.ExecuteRequest, acquireTimeout time.Duration, onOutput OutputFunc) (models.ExecuteResponse, error) {
+	pool, lang, err := e.prepare(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get container from pool: %w", err)
+		return models.ExecuteResponse{}, err
 	}
 
-	fileName, err := e.createTempFiles(req)
+	start := time.Now()
+
+	e.logger.Printf("Executing %s code, waiting for container from pool...", req.Language)
+
+	acquireCtx, cancel := ctx, context.CancelFunc(func() {})
+	if acquireTimeout > 0 {
+		acquireCtx, cancel = context.WithTimeout(ctx, acquireTimeout)
+	}
+	lease, err := pool.Acquire(acquireCtx)
+	cancel()
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		return models.ExecuteResponse{}, fmt.Errorf("failed to get container from pool: %w", err)
 	}
-	defer os.RemoveAll(filepath.Dir(fileName))
 
-	if err := e.copyCodeToContainer(containerID, fileName, req.Language); err != nil {
-		return "", fmt.Errorf("failed to copy code to container: %w", err)
+	waited := time.Since(start)
+
+	response, err := e.runInContainer(ctx, lease.ContainerID, lang, req, newOutput(onOutput))
+	e.returnLease(lease, lang, response, err)
+	if err != nil {
+		e.logger.Printf("Code execution failed in container %s: %v", lease.ContainerID[:12], err)
+		return models.ExecuteResponse{}, fmt.Errorf("execution failed: %w", err)
 	}
 
-	e.logger.Printf("Executing %s code in container %s", req.Language, containerID[:12])
+	response.Timings.WaitMs = waited.Milliseconds()
+	response.Timings.TotalMs = time.Since(start).Milliseconds()
+
+	e.record(ctx, req, lease.ContainerID, models.Submission{
+		Status:    response.Status,
+		ExitCode:  response.ExitCode,
+		Stdout:    response.Stdout,
+		Stderr:    response.CompileOutput + response.Stderr,
+		Truncated: response.Truncated,
+		Timings:   response.Timings,
+	})
 
-	output, err := e.executeCodeInContainer(containerID, req.Language)
+	e.logger.Printf("Code execution finished in container %s with status %s (exit code %d)", lease.ContainerID[:12], response.Status, response.ExitCode)
+	return response, nil
+}
 
+// runInContainer copies the code into a leased container, compiles and runs it.
+func (e *Executor) runInContainer(ctx context.Context, containerID string, lang *language, req models.ExecuteRequest, out *output) (models.ExecuteResponse, error) {
+	files, fileName, err := e.bundle(lang, req)
 	if err != nil {
-		e.logger.Printf("Code execution failed in container %s: %v", containerID[:12], err)
-		return string(output), fmt.Errorf("execution failed: %w", err)
+		return models.ExecuteResponse{}, err
+	}
+	if err := e.copyFilesToContainer(ctx, containerID, files); err != nil {
+		return models.ExecuteResponse{}, fmt.Errorf("failed to copy code to container: %w", err)
 	}
 
-	e.logger.Printf("Code execution completed successfully in container %s", containerID[:12])
-	return string(output), nil
+	e.logger.Printf("Executing %s code in container %s", req.Language, containerID[:12])
+
+	return e.executeCodeInContainer(ctx, containerID, lang, fileName, files, req, out)
 }
 
 // Shutdown gracefully shuts down the executor and cleans up all containers
@@ -129,86 +338,346 @@ func (e *Executor) IsShutdown() bool {
 	return e.shutdown
 }
 
-func getContainerFromPool(pool *ContainerPool, language string) (string, error) {
-	if pool.IsShutdown() {
-		return "", fmt.Errorf("container pool is shutting down")
-	}
-
-	var containerID string
-	select {
-	case containerID = <-pool.containers:
-		pool.logger.Printf("Acquired container %s for %s execution", containerID[:12], language)
-		defer func() {
-			// Only return container to pool if not shutting down
-			if !pool.IsShutdown() {
-				pool.containers <- containerID
-				pool.logger.Printf("Returned container %s to %s pool", containerID[:12], language)
-			}
-		}()
-	case <-time.After(5 * time.Second):
-		return "", fmt.Errorf("no containers available, pool exhausted")
+// returnLease ends the lease of a container after a job. Containers of
+// recycling languages and containers whose job failed, timed out or could
+// not be reset are discarded; all others are released for the next job.
+func (e *Executor) returnLease(lease *Lease, lang *language, response models.ExecuteResponse, err error) {
+	switch {
+	case err != nil:
+		lease.Discard("job failed: " + err.Error())
+	case response.Status == models.StatusTimeout:
+		lease.Discard("job timed out")
+	case lang.recycles():
+		lease.Discard("recycled after use")
+	default:
+		if err := e.resetContainer(lease.ContainerID); err != nil {
+			lease.Discard("reset failed: " + err.Error())
+			return
+		}
+		lease.Release()
+	}
+}
+
+// resetContainer kills all processes of the last job and empties the
+// working directory and /tmp.
+func (e *Executor) resetContainer(containerID string) error {
+	return e.runScript(context.Background(), containerID, resetScript)
+}
+
+// runScript runs a shell script in the container and fails unless it exits
+// with 0.
+func (e *Executor) runScript(ctx context.Context, containerID string, script string) error {
+	var output bytes.Buffer
+	result, err := e.runtime.Exec(ctx, containerID, ExecOptions{
+		Cmd:    []string{"sh", "-c", script},
+		Stdout: &output,
+		Stderr: &output,
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("script exited with %d: %s", result.ExitCode, strings.TrimSpace(output.String()))
 	}
-	return containerID, nil
+	return nil
 }
 
-func (e *Executor) createTempFiles(req models.ExecuteRequest) (string, error) {
-	tempDir, err := os.MkdirTemp("", fmt.Sprintf("codeexec_%d_%d", time.Now().UnixNano(), rand.Int63()))
+// Languages returns the registry entries of all supported languages, sorted by name.
+func (e *Executor) Languages() []models.Language {
+	languages := make([]models.Language, 0, len(e.languages))
+	for _, lang := range e.languages {
+		languages = append(languages, lang.Language)
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i].Name < languages[j].Name })
+	return languages
+}
+
+// copyFilesFromContainer returns the files in the working directory.
+func (e *Executor) copyFilesFromContainer(ctx context.Context, containerID string) ([]archiveFile, error) {
+	archive, err := e.runtime.CopyOut(ctx, containerID, workDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, err
 	}
+	defer archive.Close()
+	return untarFiles(archive)
+}
 
-	fileName := filepath.Join(tempDir, fmt.Sprintf("script.%s", models.LanguageToExtension[req.Language]))
-	if err := os.WriteFile(fileName, []byte(req.Code), 0644); err != nil {
-		return "", fmt.Errorf("failed to write code file: %w", err)
+func (e *Executor) copyFilesToContainer(ctx context.Context, containerID string, files []archiveFile) error {
+	archive, err := tarFiles(files)
+	if err != nil {
+		return err
 	}
+	return e.runtime.CopyIn(ctx, containerID, workDir, archive)
+}
 
-	return fileName, nil
+// executeCodeInContainer compiles the code, if lang needs it, and runs it,
+// each under its own time limit.
+func (e *Executor) executeCodeInContainer(ctx context.Context, containerID string, lang *language, fileName string, files []archiveFile, req models.ExecuteRequest, out *output) (models.ExecuteResponse, error) {
+	var compileOutput string
+	var compileMs int64
+	if len(lang.Compile) > 0 {
+		var output bytes.Buffer
+		limit := newOutputLimit(e.maxOutputBytes, nil)
+		started := time.Now()
+		status, result, err := e.compile(ctx, containerID, lang, fileName, files, limit.writer(out.writer("compile", &output)))
+		compileMs = time.Since(started).Milliseconds()
+		if err != nil {
+			return models.ExecuteResponse{}, err
+		}
+		compileOutput = output.String()
+		if status != models.StatusOK {
+			return models.ExecuteResponse{
+				CompileOutput: compileOutput,
+				ExitCode:      result.ExitCode,
+				Status:        status,
+				Truncated:     limit.exceeded(),
+				Timings:       models.Timings{CompileMs: compileMs},
+			}, nil
+		}
+	}
+
+	response, err := e.runCode(ctx, containerID, lang, fileName, req.Stdin, e.runTimeout(lang), out)
+	response.CompileOutput = compileOutput
+	response.Timings.CompileMs = compileMs
+	return response, err
+}
+
+// runTimeout returns how long code of lang may run.
+func (e *Executor) runTimeout(lang *language) time.Duration {
+	if lang.Timeout > 0 {
+		return time.Duration(lang.Timeout) * time.Second
+	}
+	return e.timeout
 }
 
-func (e *Executor) copyCodeToContainer(containerID string, fileName string, language string) error {
-	copyCmd := exec.Command("docker", "cp", fileName, containerID+":/tmp/script."+models.LanguageToExtension[language])
-	return copyCmd.Run()
+// compileTimeout returns how long compiling code of lang may take, which
+// is as long as it may run unless the language sets its own limit.
+func (e *Executor) compileTimeout(lang *language) time.Duration {
+	if lang.CompileTimeout > 0 {
+		return time.Duration(lang.CompileTimeout) * time.Second
+	}
+	return e.runTimeout(lang)
+}
+
+// runCode runs compiled code once, feeding it stdin if not empty, for at
+// most timeout. Code writing more than the output limit is stopped with
+// StatusOutputLimitExceeded; like after a timeout, its processes may still
+// be running in the container.
+func (e *Executor) runCode(ctx context.Context, containerID string, lang *language, fileName string, stdin string, timeout time.Duration, out *output) (models.ExecuteResponse, error) {
+	var input io.Reader
+	if stdin != "" {
+		input = strings.NewReader(stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	var result ExecResult
+	var status models.ExecutionStatus
+	var limit *outputLimit
+	var err error
+	runCmd := lang.command(lang.Run, fileName)
+	usage := e.measure(ctx, containerID, func() {
+		runCtx, kill := context.WithTimeout(ctx, timeout)
+		defer kill()
+		limit = newOutputLimit(e.maxOutputBytes, kill)
+
+		result, err = e.runtime.Exec(runCtx, containerID, ExecOptions{
+			Cmd:     runCmd,
+			WorkDir: workDir,
+			Stdin:   input,
+			Stdout:  limit.writer(out.writer("stdout", &stdout)),
+			Stderr:  limit.writer(out.writer("stderr", &stderr)),
+		})
+		status, err = executionStatus(runCtx, result, err)
+	})
+
+	if limit.exceeded() {
+		status, err = models.StatusOutputLimitExceeded, nil
+	}
+	if err != nil {
+		return models.ExecuteResponse{}, fmt.Errorf("failed to execute code in container: %w", err)
+	}
+
+	return models.ExecuteResponse{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  result.ExitCode,
+		Status:    status,
+		Truncated: limit.exceeded(),
+		Usage:     usage,
+		Timings:   models.Timings{RunMs: usage.WallMs},
+	}, nil
 }
 
-func (e *Executor) executeCodeInContainer(containerID string, language string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+// compile runs compileCode under the compile timeout of lang for the files
+// copied to the container. With an artifact cache, the files a successful
+// compilation produced are stored and copied in the next time the same
+// files are compiled, along with the compiler's output.
+func (e *Executor) compile(ctx context.Context, containerID string, lang *language, fileName string, files []archiveFile, w io.Writer) (models.ExecutionStatus, ExecResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.compileTimeout(lang))
 	defer cancel()
 
-	var execCmd *exec.Cmd
-	switch language {
-	case "python3":
-		execCmd = exec.CommandContext(ctx, "docker", "exec", containerID, "python3", "/tmp/script.py")
-	case "nodejs":
-		execCmd = exec.CommandContext(ctx, "docker", "exec", containerID, "node", "/tmp/script.js")
-	case "java":
-		// Java requires compilation first
-		compileCmd := exec.CommandContext(ctx, "docker", "exec", containerID, "javac", "/tmp/script.java")
-		if err := compileCmd.Run(); err != nil {
-			return "", fmt.Errorf("java compilation failed: %w", err)
+	if e.artifacts == nil {
+		return e.compileCode(ctx, containerID, lang, fileName, w)
+	}
+
+	// Images rebuilt under the same name may compile differently
+	info, err := e.runtime.Inspect(ctx, containerID)
+	if err != nil {
+		e.logger.Printf("Compiling %s code in container %s without the artifact cache: %v", lang.Name, containerID[:12], err)
+		return e.compileCode(ctx, containerID, lang, fileName, w)
+	}
+
+	key := artifactKey(lang, info.ImageID, fileName, files)
+	if cached, ok := e.artifacts.get(key); ok {
+		if err := e.copyFilesToContainer(ctx, containerID, cached.Files); err != nil {
+			return "", ExecResult{}, fmt.Errorf("failed to copy cached build to container: %w", err)
 		}
-		execCmd = exec.CommandContext(ctx, "docker", "exec", containerID, "java", "-cp", "/tmp", "script")
-	case "cpp":
-		// C++ requires compilation first
-		compileCmd := exec.CommandContext(ctx, "docker", "exec", containerID, "g++", "/tmp/script.cpp", "-o", "/tmp/script")
-		if err := compileCmd.Run(); err != nil {
-			return "", fmt.Errorf("cpp compilation failed: %w", err)
+		e.logger.Printf("Using cached %s build %s in container %s", lang.Name, key[:12], containerID[:12])
+		io.WriteString(w, cached.Output)
+		return models.StatusOK, ExecResult{}, nil
+	}
+
+	var output bytes.Buffer
+	status, result, err := e.compileCode(ctx, containerID, lang, fileName, io.MultiWriter(w, newOutputLimit(e.maxOutputBytes, nil).writer(&output)))
+	if err == nil && status == models.StatusOK {
+		if err := e.cacheBuild(ctx, containerID, key, files, output.String()); err != nil {
+			e.logger.Printf("Failed to cache %s build in container %s: %v", lang.Name, containerID[:12], err)
 		}
-		execCmd = exec.CommandContext(ctx, "docker", "exec", containerID, "/tmp/script")
-	case "go":
-		execCmd = exec.CommandContext(ctx, "docker", "exec", containerID, "go", "run", "/tmp/script.go")
-	default:
-		return "", fmt.Errorf("unsupported language: %s", language)
 	}
+	return status, result, err
+}
 
-	output, err := execCmd.CombinedOutput()
+// cacheBuild stores what compiling files left in the working directory.
+func (e *Executor) cacheBuild(ctx context.Context, containerID string, key string, files []archiveFile, output string) error {
+	archive, err := e.runtime.CopyOut(ctx, containerID, workDir)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
 
-	// Cleanup
-	cleanupCmd := exec.Command("docker", "exec", containerID, "rm", "-f", "/tmp/script*")
-	cleanupCmd.Run()
+	build, err := buildArtifact(archive, files, output)
+	if err != nil {
+		return err
+	}
+	return e.artifacts.put(key, build)
+}
 
+// compileCode runs the compile command of lang and writes its output to w.
+// Failures of the compiler are reported as StatusCompileError.
+func (e *Executor) compileCode(ctx context.Context, containerID string, lang *language, fileName string, w io.Writer) (models.ExecutionStatus, ExecResult, error) {
+	compileCmd := lang.command(lang.Compile, fileName)
+	result, err := e.runtime.Exec(ctx, containerID, ExecOptions{Cmd: compileCmd, WorkDir: workDir, Stdout: w, Stderr: w})
+	status, err := executionStatus(ctx, result, err)
 	if err != nil {
-		return string(output), fmt.Errorf("failed to execute code in container: %w", err)
+		return "", result, fmt.Errorf("failed to compile code in container: %w", err)
+	}
+	if status == models.StatusRuntimeError {
+		status = models.StatusCompileError
+	}
+	return status, result, nil
+}
+
+// output forwards program output to an OutputFunc while it is collected.
+type output struct {
+	mu       sync.Mutex
+	onOutput OutputFunc
+}
+
+func newOutput(onOutput OutputFunc) *output {
+	if onOutput == nil {
+		return nil
+	}
+	return &output{onOutput: onOutput}
+}
+
+// writer returns a writer collecting into buf that also forwards to the
+// OutputFunc, if there is one.
+func (o *output) writer(stream string, buf *bytes.Buffer) io.Writer {
+	if o == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, streamWriter{o, stream})
+}
+
+// outputLimit caps the output of a command, summed over its streams. Output
+// past the limit is dropped and calls onExceeded once.
+type outputLimit struct {
+	mu         sync.Mutex
+	remaining  int
+	cut        bool
+	onExceeded func()
+}
+
+func newOutputLimit(limit int, onExceeded func()) *outputLimit {
+	return &outputLimit{remaining: limit, onExceeded: onExceeded}
+}
+
+// writer returns a writer passing output to w while within the limit.
+func (l *outputLimit) writer(w io.Writer) io.Writer {
+	return limitedWriter{l, w}
+}
+
+// exceeded reports whether output has been dropped.
+func (l *outputLimit) exceeded() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cut
+}
+
+type limitedWriter struct {
+	limit *outputLimit
+	w     io.Writer
+}
+
+// Write reports success for dropped output too, so that runtimes keep
+// draining the streams until the program is killed.
+func (w limitedWriter) Write(p []byte) (int, error) {
+	l := w.limit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := len(p)
+	if n > l.remaining {
+		p = p[:l.remaining]
+		if !l.cut {
+			l.cut = true
+			if l.onExceeded != nil {
+				l.onExceeded()
+			}
+		}
 	}
+	l.remaining -= len(p)
+	if len(p) > 0 {
+		w.w.Write(p)
+	}
+	return n, nil
+}
+
+type streamWriter struct {
+	output *output
+	stream string
+}
 
-	return string(output), nil
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.output.mu.Lock()
+	defer w.output.mu.Unlock()
+	w.output.onOutput(w.stream, p)
+	return len(p), nil
+}
+
+// executionStatus classifies the outcome of a command run under ctx. Only
+// failures to run the command at all are returned as errors.
+func executionStatus(ctx context.Context, result ExecResult, err error) (models.ExecutionStatus, error) {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return models.StatusTimeout, nil
+	case err != nil:
+		return "", err
+	case result.ExitCode == 0:
+		return models.StatusOK, nil
+	case result.ExitCode == exitCodeSIGKILL:
+		return models.StatusKilled, nil
+	default:
+		return models.StatusRuntimeError, nil
+	}
 }
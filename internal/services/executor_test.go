@@ -0,0 +1,562 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// testLanguages are an interpreted and a compiled language, whose commands
+// are answered by the program of the test runtime.
+var testLanguages = map[string]models.Language{
+	"python3": {
+		Name:     "python3",
+		Version:  "3.12",
+		Image:    "sandbox-python",
+		FileName: "main.py",
+		Run:      []string{"python3", "{{file}}"},
+	},
+	"cpp": {
+		Name:     "cpp",
+		Version:  "13",
+		Image:    "sandbox-cpp",
+		FileName: "main.cpp",
+		Compile:  []string{"g++", "{{file}}", "-o", "{{dir}}/{{name}}"},
+		Run:      []string{"{{dir}}/{{name}}"},
+	},
+}
+
+// program answers the compile and run commands of code in a fake container.
+type program func(c *FakeContainer, opts ExecOptions) (ExecResult, error)
+
+// newTestRuntime returns a FakeRuntime passing the commands of code to run,
+// reporting the idle counters of a cgroup whose peak can be reset and
+// answering the executor's resets by deleting the files under the
+// directories the reset script names.
+func newTestRuntime(run program) *FakeRuntime {
+	rt := NewFakeRuntime()
+	rt.ExecFunc = func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] != "sh" {
+			return run(c, opts)
+		}
+		if opts.Cmd[2] == usageScript {
+			io.WriteString(opts.Stdout, "0 0 0 1\n")
+		}
+		if opts.Cmd[2] == resetScript {
+			for name := range c.Files {
+				for _, dir := range resetDirs() {
+					if strings.HasPrefix(name, dir+"/") {
+						delete(c.Files, name)
+					}
+				}
+			}
+		}
+		return ExecResult{}, nil
+	}
+	return rt
+}
+
+// resetDirs returns the directories resetScript empties.
+func resetDirs() []string {
+	_, find, _ := strings.Cut(resetScript, "find ")
+	dirs, _, _ := strings.Cut(find, " -mindepth")
+	return strings.Fields(dirs)
+}
+
+// echo copies stdin to stdout.
+func echo(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+	if opts.Stdin != nil {
+		io.Copy(opts.Stdout, opts.Stdin)
+	}
+	return ExecResult{}, nil
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newTestExecutor returns an executor whose commands are answered by rt,
+// once all of its containers are created. Unless cfg sets them, it runs the
+// test languages with a single container per language and a one second
+// time limit.
+func newTestExecutor(t *testing.T, rt Runtime, cfg ExecutorConfig) *Executor {
+	t.Helper()
+	if cfg.Languages == nil {
+		cfg.Languages = testLanguages
+	}
+	if cfg.MaxConcurrent == 0 {
+		cfg.MaxConcurrent = 1
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	e := NewExecutor(rt, cfg, log.New(io.Discard, "", 0))
+	t.Cleanup(e.Shutdown)
+
+	for _, pool := range e.pools {
+		containers := make([]string, cfg.MaxConcurrent)
+		for i := range containers {
+			containers[i] = <-pool.containers
+		}
+		for _, id := range containers {
+			pool.containers <- id
+		}
+	}
+	return e
+}
+
+func TestExecuteRunsCodeInPoolContainer(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		io.WriteString(opts.Stdout, "ran "+string(c.Files[opts.Cmd[1]]))
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Stdout != "ran print(1)" {
+		t.Errorf("got stdout %q, want the code run from its script file", response.Stdout)
+	}
+
+	for _, id := range rt.Containers() {
+		c := rt.Container(id)
+		if c.Spec.Image != "sandbox-python" {
+			continue
+		}
+		if !c.Spec.NetworkDisabled {
+			t.Error("python3 container has network access")
+		}
+		execs := slices.DeleteFunc(slices.Clone(c.Execs), func(cmd []string) bool {
+			return cmd[0] == "sh" && cmd[2] == usageScript
+		})
+		if len(execs) != 2 || execs[1][2] != resetScript {
+			t.Errorf("got commands %v, want the run followed by the reset", execs)
+		}
+	}
+}
+
+func TestExecuteStatus(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		status   models.ExecutionStatus
+	}{
+		{0, models.StatusOK},
+		{1, models.StatusRuntimeError},
+		{exitCodeSIGKILL, models.StatusKilled},
+	}
+
+	for _, test := range tests {
+		rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+			io.WriteString(opts.Stdout, "out\n")
+			io.WriteString(opts.Stderr, "err\n")
+			return ExecResult{ExitCode: test.exitCode}, nil
+		})
+		e := newTestExecutor(t, rt, ExecutorConfig{})
+
+		response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "run()"})
+		if err != nil {
+			t.Fatalf("exit code %d: Execute: %v", test.exitCode, err)
+		}
+		response.Usage.WallMs, response.Timings = 0, models.Timings{}
+		want := models.ExecuteResponse{Stdout: "out\n", Stderr: "err\n", ExitCode: test.exitCode, Status: test.status}
+		if response != want {
+			t.Errorf("exit code %d: got %+v, want %+v", test.exitCode, response, want)
+		}
+	}
+}
+
+func TestExecuteUnsupportedLanguage(t *testing.T) {
+	e := newTestExecutor(t, NewFakeRuntime(), ExecutorConfig{})
+	if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "cobol", Code: "DISPLAY 1"}); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("got error %v, want ErrUnsupportedLanguage", err)
+	}
+}
+
+func TestExecuteStdin(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(echo), ExecutorConfig{MaxStdinBytes: 10})
+
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(input())", Stdin: "1 2 3\n"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Stdout != "1 2 3\n" {
+		t.Errorf("got stdout %q, want the request's stdin", response.Stdout)
+	}
+
+	if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(input())", Stdin: "1 2 3 4 5 6\n"}); !errors.Is(err, ErrStdinTooLarge) {
+		t.Errorf("got error %v for stdin over the limit, want ErrStdinTooLarge", err)
+	}
+}
+
+func TestExecuteCompiledLanguage(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		switch opts.Cmd[0] {
+		case "g++":
+			if string(c.Files[opts.Cmd[1]]) != "int main() {}" {
+				io.WriteString(opts.Stderr, "main.cpp:1: error\n")
+				return ExecResult{ExitCode: 1}, nil
+			}
+			io.WriteString(opts.Stderr, "main.cpp:1: warning\n")
+			c.Files[opts.Cmd[3]] = []byte("binary")
+		case workDir + "/main":
+			if _, ok := c.Files[opts.Cmd[0]]; !ok {
+				return ExecResult{ExitCode: 127}, nil
+			}
+			io.WriteString(opts.Stdout, "ran\n")
+		}
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "cpp", Code: "int main() {}"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Status != models.StatusOK || response.Stdout != "ran\n" {
+		t.Errorf("got status %s and stdout %q, want the compiled program to run", response.Status, response.Stdout)
+	}
+	if response.CompileOutput != "main.cpp:1: warning\n" || response.Stderr != "" {
+		t.Errorf("got compile output %q and stderr %q, want the warning apart from the program's output", response.CompileOutput, response.Stderr)
+	}
+
+	response, err = e.Execute(context.Background(), models.ExecuteRequest{Language: "cpp", Code: "int main("})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	response.Usage.WallMs, response.Timings = 0, models.Timings{}
+	want := models.ExecuteResponse{CompileOutput: "main.cpp:1: error\n", ExitCode: 1, Status: models.StatusCompileError}
+	if response != want {
+		t.Errorf("got %+v, want %+v", response, want)
+	}
+}
+
+func TestExecuteStreamsCompileOutput(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "g++" {
+			io.WriteString(opts.Stderr, "main.cpp:1: warning\n")
+			return ExecResult{}, nil
+		}
+		io.WriteString(opts.Stdout, "ran\n")
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	var streamed []string
+	_, err := e.ExecuteStream(context.Background(), models.ExecuteRequest{Language: "cpp", Code: "int main() {}"}, func(stream string, data []byte) {
+		streamed = append(streamed, stream+": "+string(data))
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	want := []string{"compile: main.cpp:1: warning\n", "stdout: ran\n"}
+	if !slices.Equal(streamed, want) {
+		t.Errorf("streamed %q, want %q", streamed, want)
+	}
+}
+
+func TestExecuteCompileTimeLimit(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		time.Sleep(300 * time.Millisecond)
+		return ExecResult{}, nil
+	})
+	cpp := testLanguages["cpp"]
+	slowCpp := cpp
+	slowCpp.Name, slowCpp.CompileTimeout = "slow-cpp", 1
+	e := newTestExecutor(t, rt, ExecutorConfig{
+		Languages: map[string]models.Language{"cpp": cpp, "slow-cpp": slowCpp},
+		Timeout:   200 * time.Millisecond,
+	})
+
+	// Compiling takes longer than running may, which limits compiling too
+	// unless the language sets a compile timeout of its own
+	tests := []struct {
+		language string
+		compiled bool
+	}{
+		{"cpp", false},
+		{"slow-cpp", true},
+	}
+	for _, test := range tests {
+		response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: test.language, Code: "int main() {}"})
+		if err != nil {
+			t.Fatalf("%s: Execute: %v", test.language, err)
+		}
+		if response.Status != models.StatusTimeout {
+			t.Errorf("%s: got status %s, want %s", test.language, response.Status, models.StatusTimeout)
+		}
+		if compiled := response.Timings.CompileMs >= 300; compiled != test.compiled {
+			t.Errorf("%s: compiled in %d ms, want compiling finished %t", test.language, response.Timings.CompileMs, test.compiled)
+		}
+	}
+}
+
+func TestExecuteProject(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		io.WriteString(opts.Stdout, strings.Join(opts.Cmd, " ")+"\n")
+		io.WriteString(opts.Stdout, string(c.Files[workDir+"/app/run.py"])+string(c.Files[workDir+"/app/util.py"]))
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{
+		Language:   "python3",
+		Entrypoint: "app/run.py",
+		Files: []models.File{
+			{Path: "app/run.py", Content: "import util\n"},
+			{Path: "app/util.py", Content: "eA==", Encoding: models.FileEncodingBase64},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if want := "python3 " + workDir + "/app/run.py\nimport util\nx"; response.Stdout != want {
+		t.Errorf("got stdout %q, want the entrypoint run with the files in place (%q)", response.Stdout, want)
+	}
+}
+
+func TestExecuteDiscardsContainer(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    program
+		status models.ExecutionStatus
+	}{
+		{
+			name: "timeout",
+			run: func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+				time.Sleep(time.Second)
+				return ExecResult{}, nil
+			},
+			status: models.StatusTimeout,
+		},
+		{
+			name: "error",
+			run: func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+				return ExecResult{ExitCode: -1}, errors.New("connection reset")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			used := make(chan string, 1)
+			rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+				used <- c.ID
+				return test.run(c, opts)
+			})
+			e := newTestExecutor(t, rt, ExecutorConfig{Timeout: 5 * time.Millisecond})
+
+			response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "run()"})
+			if test.status == "" && err == nil {
+				t.Fatalf("Execute succeeded with status %s, want an error", response.Status)
+			}
+			if test.status != "" && (err != nil || response.Status != test.status) {
+				t.Fatalf("Execute: got status %s and error %v, want %s", response.Status, err, test.status)
+			}
+
+			// The container is removed and replaced by a new one
+			containerID := <-used
+			waitFor(t, "replacement container", func() bool {
+				containers := rt.Containers()
+				return !slices.Contains(containers, containerID) && len(containers) == 2
+			})
+		})
+	}
+}
+
+func TestExecuteReusesResetContainer(t *testing.T) {
+	var seen []string
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		seen = append(seen, c.ID)
+		if len(c.Files) != 1 {
+			return ExecResult{ExitCode: 1}, nil
+		}
+		return echo(c, opts)
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	for i := range 2 {
+		stdin := fmt.Sprintf("run %d\n", i)
+		response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(input())", Stdin: stdin})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if response.Status != models.StatusOK || response.Stdout != stdin {
+			t.Errorf("run %d: got status %s and stdout %q, want ok and %q", i, response.Status, response.Stdout, stdin)
+		}
+	}
+	if len(seen) != 2 || seen[0] != seen[1] {
+		t.Errorf("runs used containers %v, want the same one twice", seen)
+	}
+}
+
+func TestExecuteRecyclesContainer(t *testing.T) {
+	python := testLanguages["python3"]
+	python.Isolation = models.IsolationRecycle
+	var seen []string
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		seen = append(seen, c.ID)
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{Languages: map[string]models.Language{"python3": python}})
+
+	for range 2 {
+		if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	if len(seen) != 2 || seen[0] == seen[1] {
+		t.Errorf("runs used containers %v, want a new one for every run", seen)
+	}
+}
+
+func TestResetEmptiesEveryWritableMount(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		// Docker gives every container a writable /dev/shm besides its tmpfs mounts.
+		mounts := []string{"/dev/shm"}
+		for mountPoint := range c.Spec.Tmpfs {
+			mounts = append(mounts, mountPoint)
+		}
+		for _, mountPoint := range mounts {
+			if _, ok := c.Files[mountPoint+"/left"]; ok {
+				io.WriteString(opts.Stdout, mountPoint+" ")
+			}
+			c.Files[mountPoint+"/left"] = []byte("state")
+		}
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	for i := range 2 {
+		response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "leave()"})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if response.Stdout != "" {
+			t.Errorf("run %d found files left in %s", i, response.Stdout)
+		}
+	}
+	for _, id := range rt.Containers() {
+		if spec := rt.Container(id).Spec; !spec.ReadOnly {
+			t.Errorf("%s container has a writable root filesystem", spec.Image)
+		}
+	}
+}
+
+func TestPoolContainersUseLanguageLimits(t *testing.T) {
+	languages := map[string]models.Language{
+		"python3": testLanguages["python3"],
+		"cpp":     testLanguages["cpp"],
+	}
+	cpp := languages["cpp"]
+	cpp.Limits = models.LanguageLimits{CPUs: 2, MemoryMB: 256}
+	languages["cpp"] = cpp
+
+	tests := []struct {
+		name string
+		cfg  ExecutorConfig
+		want map[string]ContainerSpec
+	}{
+		{
+			name: "defaults",
+			cfg:  ExecutorConfig{Languages: languages},
+			want: map[string]ContainerSpec{
+				"sandbox-python": {CPUs: DefaultCPUs, MemoryMB: DefaultMemoryMB},
+				"sandbox-cpp":    {CPUs: 2, MemoryMB: 256},
+			},
+		},
+		{
+			name: "configured",
+			cfg:  ExecutorConfig{Languages: languages, CPUs: 1, MemoryMB: 64},
+			want: map[string]ContainerSpec{
+				"sandbox-python": {CPUs: 1, MemoryMB: 64},
+				"sandbox-cpp":    {CPUs: 2, MemoryMB: 256},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		rt := newTestRuntime(echo)
+		newTestExecutor(t, rt, test.cfg)
+
+		for _, id := range rt.Containers() {
+			spec := rt.Container(id).Spec
+			want := test.want[spec.Image]
+			if spec.CPUs != want.CPUs || spec.MemoryMB != want.MemoryMB {
+				t.Errorf("%s: %s container has %g CPUs and %d MB, want %g and %d", test.name, spec.Image, spec.CPUs, spec.MemoryMB, want.CPUs, want.MemoryMB)
+			}
+		}
+	}
+}
+
+func TestShutdownRemovesContainers(t *testing.T) {
+	rt := NewFakeRuntime()
+	e := newTestExecutor(t, rt, ExecutorConfig{MaxConcurrent: 2})
+	if created := len(rt.Containers()); created != 2*len(testLanguages) {
+		t.Fatalf("created %d containers, want 2 per language", created)
+	}
+	e.Shutdown()
+
+	if containers := rt.Containers(); len(containers) != 0 {
+		t.Errorf("%d containers left after shutdown", len(containers))
+	}
+	if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Execute after shutdown: got %v, want ErrShuttingDown", err)
+	}
+}
+
+func TestExecuteOutputLimit(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "g++" {
+			io.WriteString(opts.Stderr, strings.Repeat("main.cpp:1: error\n", 100))
+			return ExecResult{ExitCode: 1}, nil
+		}
+		// Write until killed, or for a second at most
+		for range 1000 {
+			io.WriteString(opts.Stdout, strings.Repeat("o", 10))
+			io.WriteString(opts.Stderr, strings.Repeat("e", 10))
+			time.Sleep(time.Millisecond)
+		}
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{MaxOutputBytes: 100, Timeout: 5 * time.Second})
+
+	start := time.Now()
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "spam()"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Status != models.StatusOutputLimitExceeded || !response.Truncated {
+		t.Errorf("got status %s (truncated %t), want %s", response.Status, response.Truncated, models.StatusOutputLimitExceeded)
+	}
+	if n := len(response.Stdout) + len(response.Stderr); n != 100 {
+		t.Errorf("got %d bytes of output, want the limit of 100", n)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Execute took %s, want the program killed at the limit", elapsed)
+	}
+
+	response, err = e.Execute(context.Background(), models.ExecuteRequest{Language: "cpp", Code: "int main("})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Status != models.StatusCompileError || !response.Truncated || len(response.CompileOutput) != 100 {
+		t.Errorf("got status %s with %d bytes of output (truncated %t), want a compile error cut at 100 bytes", response.Status, len(response.CompileOutput), response.Truncated)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// DefaultMaxBundleBytes is used when ExecutorConfig.MaxBundleBytes is not set.
+const DefaultMaxBundleBytes = 4 << 20
+
+// maxBundleFiles limits the number of files of an execution.
+const maxBundleFiles = 100
+
+var (
+	ErrInvalidFiles   = errors.New("invalid files")
+	ErrBundleTooLarge = errors.New("files exceed the size limit")
+)
+
+// bundle returns the files to upload for a request and the path of the one
+// to run, which is empty if the request has neither code nor an entrypoint.
+// Paths are checked to stay inside the working directory.
+func (e *Executor) bundle(lang *language, req models.ExecuteRequest) ([]archiveFile, string, error) {
+	if len(req.Files) > maxBundleFiles {
+		return nil, "", fmt.Errorf("%w: more than %d files", ErrInvalidFiles, maxBundleFiles)
+	}
+
+	files := make([]archiveFile, 0, len(req.Files)+1)
+	entrypoint := ""
+	switch {
+	case req.Code != "" && req.Entrypoint != "":
+		return nil, "", fmt.Errorf("%w: entrypoint cannot be combined with code", ErrInvalidFiles)
+	case req.Code != "":
+		entrypoint = lang.fileName(req.Code)
+		files = append(files, archiveFile{Name: entrypoint, Content: []byte(req.Code)})
+	case req.Entrypoint != "":
+		var err error
+		if entrypoint, err = cleanFilePath(req.Entrypoint); err != nil {
+			return nil, "", err
+		}
+	}
+
+	size := len(req.Code)
+	for _, file := range req.Files {
+		name, err := cleanFilePath(file.Path)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var content []byte
+		switch file.Encoding {
+		case "":
+			content = []byte(file.Content)
+		case models.FileEncodingBase64:
+			if content, err = base64.StdEncoding.DecodeString(file.Content); err != nil {
+				return nil, "", fmt.Errorf("%w: %s is not valid base64", ErrInvalidFiles, file.Path)
+			}
+		default:
+			return nil, "", fmt.Errorf("%w: %s has unknown encoding %q", ErrInvalidFiles, file.Path, file.Encoding)
+		}
+
+		size += len(content)
+		files = append(files, archiveFile{Name: name, Content: content})
+	}
+	if size > e.maxBundleBytes {
+		return nil, "", fmt.Errorf("%w of %d bytes", ErrBundleTooLarge, e.maxBundleBytes)
+	}
+
+	if err := checkFileTree(files); err != nil {
+		return nil, "", err
+	}
+	if req.Entrypoint != "" && !containsFile(files, entrypoint) {
+		return nil, "", fmt.Errorf("%w: entrypoint %s is not among the files", ErrInvalidFiles, req.Entrypoint)
+	}
+
+	return files, entrypoint, nil
+}
+
+// cleanFilePath normalizes a relative path and rejects paths leaving the
+// working directory.
+func cleanFilePath(p string) (string, error) {
+	if p == "" || path.IsAbs(p) || strings.ContainsAny(p, "\\\x00") {
+		return "", fmt.Errorf("%w: invalid path %q", ErrInvalidFiles, p)
+	}
+	clean := path.Clean(p)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: invalid path %q", ErrInvalidFiles, p)
+	}
+	return clean, nil
+}
+
+// checkFileTree rejects duplicate paths and files used as directories.
+func checkFileTree(files []archiveFile) error {
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		if names[file.Name] {
+			return fmt.Errorf("%w: duplicate path %s", ErrInvalidFiles, file.Name)
+		}
+		names[file.Name] = true
+	}
+	for _, file := range files {
+		for dir := path.Dir(file.Name); dir != "."; dir = path.Dir(dir) {
+			if names[dir] {
+				return fmt.Errorf("%w: %s is both a file and a directory", ErrInvalidFiles, dir)
+			}
+		}
+	}
+	return nil
+}
+
+func containsFile(files []archiveFile, name string) bool {
+	for _, file := range files {
+		if file.Name == name {
+			return true
+		}
+	}
+	return false
+}
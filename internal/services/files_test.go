@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func TestCleanFilePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.py", "main.py"},
+		{"./lib/util.py", "lib/util.py"},
+		{"lib//../main.py", "main.py"},
+		{"", ""},
+		{".", ""},
+		{"..", ""},
+		{"../main.py", ""},
+		{"lib/../../main.py", ""},
+		{"/etc/passwd", ""},
+		{`lib\util.py`, ""},
+		{"main\x00.py", ""},
+	}
+
+	for _, test := range tests {
+		got, err := cleanFilePath(test.path)
+		if test.want == "" {
+			if !errors.Is(err, ErrInvalidFiles) {
+				t.Errorf("cleanFilePath(%q) = %q, %v, want ErrInvalidFiles", test.path, got, err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("cleanFilePath(%q) = %q, %v, want %q", test.path, got, err, test.want)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	e := &Executor{maxBundleBytes: 100}
+	lang := newLanguage(testLanguages["python3"])
+
+	tests := []struct {
+		name       string
+		req        models.ExecuteRequest
+		entrypoint string
+		err        error
+	}{
+		{
+			name:       "code",
+			req:        models.ExecuteRequest{Code: "print(1)", Files: []models.File{{Path: "lib/util.py", Content: "x = 1"}}},
+			entrypoint: "main.py",
+		},
+		{
+			name:       "entrypoint",
+			req:        models.ExecuteRequest{Entrypoint: "./app/run.py", Files: []models.File{{Path: "app/run.py", Content: "aW1wb3J0IG9z", Encoding: models.FileEncodingBase64}}},
+			entrypoint: "app/run.py",
+		},
+		{
+			name: "missing entrypoint",
+			req:  models.ExecuteRequest{Entrypoint: "run.py", Files: []models.File{{Path: "main.py"}}},
+			err:  ErrInvalidFiles,
+		},
+		{
+			name: "code and entrypoint",
+			req:  models.ExecuteRequest{Code: "print(1)", Entrypoint: "main.py"},
+			err:  ErrInvalidFiles,
+		},
+		{
+			name: "duplicate",
+			req:  models.ExecuteRequest{Code: "print(1)", Files: []models.File{{Path: "./main.py"}}},
+			err:  ErrInvalidFiles,
+		},
+		{
+			name: "file as directory",
+			req:  models.ExecuteRequest{Code: "print(1)", Files: []models.File{{Path: "lib"}, {Path: "lib/util.py"}}},
+			err:  ErrInvalidFiles,
+		},
+		{
+			name: "escaping path",
+			req:  models.ExecuteRequest{Code: "print(1)", Files: []models.File{{Path: "../etc/passwd"}}},
+			err:  ErrInvalidFiles,
+		},
+		{
+			name: "bad base64",
+			req:  models.ExecuteRequest{Code: "print(1)", Files: []models.File{{Path: "data.bin", Content: "!", Encoding: models.FileEncodingBase64}}},
+			err:  ErrInvalidFiles,
+		},
+		{
+			name: "too large",
+			req:  models.ExecuteRequest{Code: "print(1)", Files: []models.File{{Path: "data.txt", Content: string(make([]byte, 100))}}},
+			err:  ErrBundleTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		_, entrypoint, err := e.bundle(lang, test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || entrypoint != test.entrypoint {
+			t.Errorf("%s: got entrypoint %q and error %v, want %q", test.name, entrypoint, err, test.entrypoint)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+	"unicode/utf8"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// maxRecordedOutput limits stdout and stderr of a recorded submission.
+const maxRecordedOutput = 64 << 10
+
+// recordTimeout bounds storing a submission, which outlives the request.
+const recordTimeout = 5 * time.Second
+
+// SubmissionRecorder stores finished executions and judgements in the
+// submission history, e.g. a store.SubmissionStore.
+type SubmissionRecorder interface {
+	CreateSubmission(ctx context.Context, submission models.Submission) (models.Submission, error)
+}
+
+// Origin tells who asked for an execution, for the submission history.
+type Origin struct {
+	User      string
+	ProblemID string
+}
+
+type originKey struct{}
+
+// WithOrigin returns a context whose executions are recorded with origin.
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// record stores a submission for req if there is a recorder. Failures are
+// only logged, they do not fail the execution.
+func (e *Executor) record(ctx context.Context, req models.ExecuteRequest, containerID string, submission models.Submission) {
+	if e.submissions == nil {
+		return
+	}
+
+	origin, _ := ctx.Value(originKey{}).(Origin)
+	submission.User = origin.User
+	submission.ProblemID = origin.ProblemID
+	submission.Language = req.Language
+	submission.Code = req.Code
+	submission.CodeHash = codeHash(req)
+	submission.ContainerID = containerID
+	submission.CreatedAt = time.Now()
+
+	var stdoutCut, stderrCut bool
+	submission.Stdout, stdoutCut = truncateOutput(submission.Stdout)
+	submission.Stderr, stderrCut = truncateOutput(submission.Stderr)
+	submission.Truncated = submission.Truncated || stdoutCut || stderrCut
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	if _, err := e.submissions.CreateSubmission(ctx, submission); err != nil {
+		e.logger.Printf("Failed to record %s submission: %v", req.Language, err)
+	}
+}
+
+// codeHash hashes the code of a request, or the paths and contents of its
+// files.
+func codeHash(req models.ExecuteRequest) string {
+	h := sha256.New()
+	if req.Code != "" {
+		h.Write([]byte(req.Code))
+	} else {
+		for _, file := range req.Files {
+			h.Write([]byte(file.Path + "\x00" + file.Encoding + "\x00" + file.Content + "\x00"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// truncateOutput cuts s to maxRecordedOutput bytes without splitting a
+// UTF-8 sequence, and reports whether it did.
+func truncateOutput(s string) (string, bool) {
+	if len(s) <= maxRecordedOutput {
+		return s, false
+	}
+	n := maxRecordedOutput
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n], true
+}
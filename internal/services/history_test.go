@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// recorder keeps the submissions it is given.
+type recorder struct {
+	mu          sync.Mutex
+	submissions []models.Submission
+}
+
+func (r *recorder) CreateSubmission(ctx context.Context, submission models.Submission) (models.Submission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	submission.ID = int64(len(r.submissions) + 1)
+	r.submissions = append(r.submissions, submission)
+	return submission, nil
+}
+
+func TestExecutorRecordsSubmissions(t *testing.T) {
+	history := &recorder{}
+	e := newTestExecutor(t, newTestRuntime(judgeProgram), ExecutorConfig{Submissions: history})
+
+	ctx := WithOrigin(context.Background(), Origin{User: "ada", ProblemID: "two-sum"})
+	if _, err := e.Execute(ctx, models.ExecuteRequest{Language: "python3", Code: "solve()", Stdin: "ac"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if _, err := e.Judge(ctx, models.JudgeRequest{Language: "python3", Code: "solve()", TestCases: []models.TestCase{{Input: "wa", Expected: "ok\n"}}}); err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+
+	if len(history.submissions) != 2 {
+		t.Fatalf("got %d recorded submissions, want 2", len(history.submissions))
+	}
+	execution, judgement := history.submissions[0], history.submissions[1]
+	if execution.User != "ada" || execution.ProblemID != "two-sum" || execution.Code != "solve()" || execution.Status != models.StatusOK || execution.Stdout != "ok\n" {
+		t.Errorf("got execution %+v, want ada's run of solve()", execution)
+	}
+	if judgement.Verdict != models.VerdictWrongAnswer || judgement.CodeHash != execution.CodeHash || len(execution.CodeHash) != 64 {
+		t.Errorf("got judgement %s with hash %q, want WA with the execution's hash %q", judgement.Verdict, judgement.CodeHash, execution.CodeHash)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	s := strings.Repeat("x", maxRecordedOutput-1) + "é"
+	got, cut := truncateOutput(s)
+	if !cut || got != s[:maxRecordedOutput-1] {
+		t.Errorf("got %d bytes (cut %t), want %d without splitting the last rune", len(got), cut, maxRecordedOutput-1)
+	}
+	if got, cut := truncateOutput("short"); cut || got != "short" {
+		t.Errorf("got %q (cut %t), want short output kept", got, cut)
+	}
+}
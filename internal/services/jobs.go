@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// Job queue settings used when JobQueueConfig leaves them unset.
+const (
+	DefaultJobQueueSize    = 1000
+	DefaultJobRetention    = 10 * time.Minute
+	DefaultMaxFinishedJobs = 10000
+)
+
+// jobSweepInterval is how often finished jobs are checked for expiry.
+const jobSweepInterval = time.Minute
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+type JobQueueConfig struct {
+	// Workers is the number of jobs executed at the same time.
+	Workers   int
+	QueueSize int
+	// Retention is how long finished jobs can still be fetched.
+	Retention time.Duration
+	// MaxFinished is how many finished jobs are kept at most. Beyond it the
+	// oldest are forgotten before their retention has passed.
+	MaxFinished int
+}
+
+// JobQueue runs executions in the background. Jobs wait in a bounded queue
+// until a worker picks them up, and a worker waits for an idle container
+// instead of failing when the pool is busy.
+type JobQueue struct {
+	executor    *Executor
+	queue       chan *job
+	retention   time.Duration
+	maxFinished int
+	logger      *log.Logger
+	mu          sync.Mutex
+	jobs        map[string]*job
+	finished    []string // IDs of finished jobs, oldest first
+	shutdown    bool
+	done        chan struct{}
+	workers     sync.WaitGroup
+}
+
+type job struct {
+	models.Job
+	request models.ExecuteRequest
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func NewJobQueue(executor *Executor, cfg JobQueueConfig, logger *log.Logger) *JobQueue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultJobQueueSize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultJobRetention
+	}
+	if cfg.MaxFinished <= 0 {
+		cfg.MaxFinished = DefaultMaxFinishedJobs
+	}
+
+	q := &JobQueue{
+		executor:    executor,
+		queue:       make(chan *job, cfg.QueueSize),
+		retention:   cfg.Retention,
+		maxFinished: cfg.MaxFinished,
+		logger:      logger,
+		jobs:        make(map[string]*job),
+		done:        make(chan struct{}),
+	}
+
+	q.workers.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go q.work()
+	}
+	go q.sweep()
+
+	logger.Printf("Job queue started with %d workers (queue size: %d, retention: %s, max finished: %d)", cfg.Workers, cfg.QueueSize, cfg.Retention, cfg.MaxFinished)
+	return q
+}
+
+// Submit validates the request and queues it. It fails with ErrQueueFull
+// instead of blocking when the queue is full. The job keeps the values of
+// ctx, like its Origin, but not its cancellation.
+func (q *JobQueue) Submit(ctx context.Context, req models.ExecuteRequest) (models.Job, error) {
+	if err := q.executor.Validate(req); err != nil {
+		return models.Job{}, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return models.Job{}, fmt.Errorf("failed to create job ID: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j := &job{
+		Job: models.Job{
+			ID:        id,
+			Language:  req.Language,
+			Status:    models.JobQueued,
+			CreatedAt: time.Now(),
+		},
+		request: req,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shutdown {
+		cancel()
+		return models.Job{}, fmt.Errorf("job queue %w", ErrShuttingDown)
+	}
+
+	select {
+	case q.queue <- j:
+	default:
+		cancel()
+		return models.Job{}, ErrQueueFull
+	}
+	q.jobs[id] = j
+
+	q.logger.Printf("Queued %s job %s (%d waiting)", req.Language, id, len(q.queue))
+	return j.Job, nil
+}
+
+// Get returns the current state of a job.
+func (q *JobQueue) Get(id string) (models.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return models.Job{}, ErrJobNotFound
+	}
+	return j.Job, nil
+}
+
+// Cancel cancels a queued or running job. A running job is stopped and its
+// container discarded.
+func (q *JobQueue) Cancel(id string) (models.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return models.Job{}, ErrJobNotFound
+	}
+	if j.FinishedAt != nil {
+		return j.Job, ErrJobFinished
+	}
+
+	j.cancel()
+	q.finish(j, models.JobCancelled)
+	q.logger.Printf("Cancelled job %s", id)
+	return j.Job, nil
+}
+
+// Shutdown cancels all unfinished jobs and waits for the workers to stop.
+func (q *JobQueue) Shutdown() {
+	q.mu.Lock()
+	if q.shutdown {
+		q.mu.Unlock()
+		return
+	}
+	q.shutdown = true
+	for _, j := range q.jobs {
+		if j.FinishedAt == nil {
+			j.cancel()
+			q.finish(j, models.JobCancelled)
+		}
+	}
+	close(q.queue)
+	close(q.done)
+	q.mu.Unlock()
+
+	q.workers.Wait()
+	q.logger.Printf("Job queue stopped")
+}
+
+func (q *JobQueue) work() {
+	defer q.workers.Done()
+
+	for j := range q.queue {
+		q.mu.Lock()
+		if j.Status != models.JobQueued {
+			q.mu.Unlock()
+			continue
+		}
+		now := time.Now()
+		j.Status = models.JobRunning
+		j.StartedAt = &now
+		q.mu.Unlock()
+
+		response, err := q.executor.execute(j.ctx, j.request, 0, nil)
+		j.cancel()
+
+		q.mu.Lock()
+		switch {
+		case j.Status == models.JobCancelled:
+			// Cancel already finished the job
+		case err != nil:
+			q.logger.Printf("Job %s failed: %v", j.ID, err)
+			j.Error = jobError(err)
+			q.finish(j, models.JobFailed)
+		default:
+			j.Result = &response
+			q.finish(j, models.JobCompleted)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// sweep forgets finished jobs once their retention has passed.
+func (q *JobQueue) sweep() {
+	ticker := time.NewTicker(jobSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+		}
+
+		q.mu.Lock()
+		for len(q.finished) > 0 && time.Since(*q.jobs[q.finished[0]].FinishedAt) > q.retention {
+			q.forgetOldest()
+		}
+		q.mu.Unlock()
+	}
+}
+
+// finish moves the job into a final status and forgets the oldest finished
+// job if too many are kept. q.mu must be held.
+func (q *JobQueue) finish(j *job, status models.JobStatus) {
+	now := time.Now()
+	j.Status = status
+	j.FinishedAt = &now
+
+	q.finished = append(q.finished, j.ID)
+	if len(q.finished) > q.maxFinished {
+		q.forgetOldest()
+	}
+}
+
+// forgetOldest removes the job that finished first. q.mu must be held.
+func (q *JobQueue) forgetOldest() {
+	delete(q.jobs, q.finished[0])
+	q.finished = q.finished[1:]
+}
+
+// jobError describes why a job could not be run without exposing internals.
+func jobError(err error) string {
+	if errors.Is(err, ErrShuttingDown) {
+		return "service is shutting down"
+	}
+	return "failed to execute code"
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// newTestJobQueue returns a job queue running python3 jobs with a single
+// worker and container, whose runs are answered by run.
+func newTestJobQueue(t *testing.T, run program, cfg JobQueueConfig) *JobQueue {
+	t.Helper()
+	e := newTestExecutor(t, newTestRuntime(run), ExecutorConfig{})
+	q := NewJobQueue(e, cfg, log.New(io.Discard, "", 0))
+	t.Cleanup(q.Shutdown)
+	return q
+}
+
+// waitForJob waits until a job has the given status and returns it.
+func waitForJob(t *testing.T, q *JobQueue, id string, status models.JobStatus) models.Job {
+	t.Helper()
+	var j models.Job
+	waitFor(t, "job "+id+" to be "+string(status), func() bool {
+		var err error
+		j, err = q.Get(id)
+		return err == nil && j.Status == status
+	})
+	return j
+}
+
+func TestJobQueueRunsJobs(t *testing.T) {
+	q := newTestJobQueue(t, echo, JobQueueConfig{})
+
+	j, err := q.Submit(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(input())", Stdin: "hi\n"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if j.Status != models.JobQueued {
+		t.Errorf("got status %s for a submitted job, want %s", j.Status, models.JobQueued)
+	}
+
+	j = waitForJob(t, q, j.ID, models.JobCompleted)
+	if j.Result == nil || j.Result.Stdout != "hi\n" || j.StartedAt == nil || j.FinishedAt == nil {
+		t.Errorf("got completed job %+v, want its result and times", j)
+	}
+	if _, err := q.Cancel(j.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel of a finished job: got %v, want ErrJobFinished", err)
+	}
+	if _, err := q.Submit(context.Background(), models.ExecuteRequest{Language: "cobol", Code: "DISPLAY 1"}); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Submit of an unsupported language: got %v, want ErrUnsupportedLanguage", err)
+	}
+}
+
+func TestJobQueueFullAndCancel(t *testing.T) {
+	unblock := make(chan struct{})
+	q := newTestJobQueue(t, func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		<-unblock
+		return ExecResult{}, nil
+	}, JobQueueConfig{QueueSize: 1})
+
+	running, err := q.Submit(context.Background(), models.ExecuteRequest{Language: "python3", Code: "block()"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitForJob(t, q, running.ID, models.JobRunning)
+
+	queued, err := q.Submit(context.Background(), models.ExecuteRequest{Language: "python3", Code: "block()"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if _, err := q.Submit(context.Background(), models.ExecuteRequest{Language: "python3", Code: "block()"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit to a full queue: got %v, want ErrQueueFull", err)
+	}
+
+	j, err := q.Cancel(queued.ID)
+	if err != nil || j.Status != models.JobCancelled {
+		t.Fatalf("Cancel: got status %s and error %v, want %s", j.Status, err, models.JobCancelled)
+	}
+	close(unblock)
+
+	waitForJob(t, q, running.ID, models.JobCompleted)
+	if j, _ := q.Get(queued.ID); j.Status != models.JobCancelled || j.StartedAt != nil {
+		t.Errorf("got cancelled job %+v, want it never started", j)
+	}
+}
+
+func TestJobQueueForgetsOldestFinishedJobs(t *testing.T) {
+	q := newTestJobQueue(t, echo, JobQueueConfig{MaxFinished: 2})
+
+	var ids []string
+	for range 3 {
+		j, err := q.Submit(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"})
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		waitForJob(t, q, j.ID, models.JobCompleted)
+		ids = append(ids, j.ID)
+	}
+
+	if _, err := q.Get(ids[0]); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get of the oldest finished job: got %v, want ErrJobNotFound", err)
+	}
+	for _, id := range ids[1:] {
+		if _, err := q.Get(id); err != nil {
+			t.Errorf("Get of a newer finished job: %v", err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// MaxTestCases limits the number of test cases of a judge request.
+const MaxTestCases = 100
+
+var (
+	ErrInvalidTests  = errors.New("invalid test cases")
+	ErrInvalidLimits = errors.New("invalid limits")
+)
+
+// Judge compiles a submission once and runs it against every test case in
+// the same container, each with the request's or the language's time limit
+// and a working directory reset to the compiled submission. A memory limit
+// of the request lowers the sandbox's while the test cases run. Submissions
+// that do not compile are judged CE without running any test case. A
+// checker judges the outputs afterwards in a container of its own, so a
+// judgement never holds two containers at once.
+func (e *Executor) Judge(ctx context.Context, req models.JudgeRequest) (models.JudgeResponse, error) {
+	pool, lang, err := e.prepare(judgeExecuteRequest(req))
+	if err != nil {
+		return models.JudgeResponse{}, err
+	}
+	if err := e.validateTests(req.TestCases); err != nil {
+		return models.JudgeResponse{}, err
+	}
+	if err := e.validateLimits(req, pool, lang); err != nil {
+		return models.JudgeResponse{}, err
+	}
+	if req.Checker != nil {
+		if _, _, err := e.prepare(checkerExecuteRequest(*req.Checker)); err != nil {
+			return models.JudgeResponse{}, fmt.Errorf("checker: %w", err)
+		}
+	}
+
+	start := time.Now()
+
+	acquireCtx, cancel := context.WithTimeout(ctx, e.acquireTimeout)
+	lease, err := pool.Acquire(acquireCtx)
+	cancel()
+	if err != nil {
+		return models.JudgeResponse{}, fmt.Errorf("failed to get container from pool: %w", err)
+	}
+
+	waited := time.Since(start)
+	e.logger.Printf("Judging %s code against %d test cases in container %s", req.Language, len(req.TestCases), lease.ContainerID[:12])
+
+	response, timedOut, err := e.judgeInContainer(ctx, lease.ContainerID, lang, req, req.MemoryLimitMB)
+	e.returnJudgeLease(lease, pool, lang, req.MemoryLimitMB, timedOut, err)
+	if err != nil {
+		e.logger.Printf("Judging failed in container %s: %v", lease.ContainerID[:12], err)
+		return models.JudgeResponse{}, fmt.Errorf("judging failed: %w", err)
+	}
+
+	if req.Checker != nil && response.Verdict != models.VerdictCompileError {
+		if err := e.check(ctx, *req.Checker, req.TestCases, response.Tests, req.StopOnFailure); err != nil {
+			return models.JudgeResponse{}, err
+		}
+	}
+	summarize(&response, req.StopOnFailure)
+
+	response.Timings.WaitMs = waited.Milliseconds()
+	response.Timings.TotalMs = time.Since(start).Milliseconds()
+
+	e.record(ctx, judgeExecuteRequest(req), lease.ContainerID, models.Submission{
+		Verdict: response.Verdict,
+		Stderr:  response.CompileOutput,
+		Timings: response.Timings,
+	})
+
+	e.logger.Printf("Judging finished in container %s with verdict %s (%d/%d passed)", lease.ContainerID[:12], response.Verdict, response.Passed, response.Total)
+	return response, nil
+}
+
+// judgeInContainer copies the submission into a leased container, compiles
+// it and runs the test cases. Test cases whose output is left to a checker
+// get no verdict yet. A positive memoryMB limits the memory of the container
+// from the first test case on. It also reports whether a test case timed
+// out, after which the container is not reused.
+func (e *Executor) judgeInContainer(ctx context.Context, containerID string, lang *language, req models.JudgeRequest, memoryMB int) (models.JudgeResponse, bool, error) {
+	response := models.JudgeResponse{Total: len(req.TestCases), Tests: []models.TestResult{}}
+
+	files, fileName, err := e.bundle(lang, judgeExecuteRequest(req))
+	if err != nil {
+		return response, false, err
+	}
+	if err := e.copyFilesToContainer(ctx, containerID, files); err != nil {
+		return response, false, fmt.Errorf("failed to copy code to container: %w", err)
+	}
+
+	if len(lang.Compile) > 0 {
+		var output bytes.Buffer
+		started := time.Now()
+		status, _, err := e.compile(ctx, containerID, lang, fileName, files, newOutputLimit(e.maxOutputBytes, nil).writer(&output))
+		response.Timings.CompileMs = time.Since(started).Milliseconds()
+		if err != nil {
+			return response, false, err
+		}
+		response.CompileOutput = output.String()
+		if status != models.StatusOK {
+			response.Verdict = models.VerdictCompileError
+			return response, status == models.StatusTimeout, nil
+		}
+	}
+
+	// Every test case starts from the compiled submission alone, without
+	// the files and processes earlier test cases left behind
+	workFiles := files
+	if len(lang.Compile) > 0 && len(req.TestCases) > 1 {
+		if workFiles, err = e.copyFilesFromContainer(ctx, containerID); err != nil {
+			return response, false, fmt.Errorf("failed to copy build from container: %w", err)
+		}
+	}
+
+	if memoryMB > 0 {
+		if err := e.runtime.SetMemory(ctx, containerID, memoryMB); err != nil {
+			return response, false, fmt.Errorf("failed to apply memory limit: %w", err)
+		}
+	}
+
+	timeLimit := e.runTimeout(lang)
+	if req.TimeLimitMs > 0 {
+		timeLimit = time.Duration(req.TimeLimitMs) * time.Millisecond
+	}
+
+	timedOut := false
+	for i, test := range req.TestCases {
+		if i > 0 {
+			if err := e.restoreWorkDir(ctx, containerID, workFiles); err != nil {
+				return response, timedOut, err
+			}
+		}
+
+		run, err := e.runCode(ctx, containerID, lang, fileName, test.Input, timeLimit, nil)
+		if err != nil {
+			return response, timedOut, err
+		}
+
+		result := models.TestResult{
+			Verdict:  runVerdict(run),
+			Stdout:   run.Stdout,
+			Stderr:   run.Stderr,
+			ExitCode: run.ExitCode,
+			TimeMs:   run.Timings.RunMs,
+			Usage:    run.Usage,
+		}
+		if result.Verdict == "" && req.Checker == nil {
+			result.Verdict = models.VerdictWrongAnswer
+			if compareOutput(run.Stdout, test.Expected, req.Comparison) {
+				result.Verdict, result.Score = models.VerdictAccepted, 1
+			}
+		}
+		response.Tests = append(response.Tests, result)
+		response.Timings.RunMs += run.Timings.RunMs
+
+		timedOut = timedOut || run.Status == models.StatusTimeout
+
+		if req.StopOnFailure && result.Verdict != "" && result.Verdict != models.VerdictAccepted {
+			break
+		}
+	}
+
+	return response, timedOut, nil
+}
+
+// summarize sets the overall verdict, score and number of passed test cases
+// from the test results. With stopOnFailure, results after the first failed
+// test case are dropped; a checker may only find that failure after all
+// test cases were run.
+func summarize(response *models.JudgeResponse, stopOnFailure bool) {
+	if response.Verdict == models.VerdictCompileError {
+		return
+	}
+	for i, result := range response.Tests {
+		response.Score += result.Score
+		if result.Verdict == models.VerdictAccepted {
+			response.Passed++
+			continue
+		}
+		if response.Verdict == "" {
+			response.Verdict = result.Verdict
+		}
+		if stopOnFailure {
+			response.Tests = response.Tests[:i+1]
+			break
+		}
+	}
+	if response.Verdict == "" {
+		response.Verdict = models.VerdictAccepted
+	}
+}
+
+// restoreWorkDir kills the processes of the last test case and empties
+// the container like a reset, leaving just files in the working directory.
+func (e *Executor) restoreWorkDir(ctx context.Context, containerID string, files []archiveFile) error {
+	if err := e.runScript(ctx, containerID, resetScript); err != nil {
+		return fmt.Errorf("failed to reset container between tests: %w", err)
+	}
+	if err := e.copyFilesToContainer(ctx, containerID, files); err != nil {
+		return fmt.Errorf("failed to copy code to container: %w", err)
+	}
+	return nil
+}
+
+// returnJudgeLease ends the lease of a judgement like returnLease, after
+// giving the container the language's memory limit back if the request
+// lowered it. Containers whose limit cannot be restored are discarded.
+func (e *Executor) returnJudgeLease(lease *Lease, pool *ContainerPool, lang *language, memoryMB int, timedOut bool, err error) {
+	if err == nil && !timedOut && memoryMB > 0 {
+		if err := e.runtime.SetMemory(context.Background(), lease.ContainerID, pool.memoryMB); err != nil {
+			lease.Discard("failed to restore memory limit: " + err.Error())
+			return
+		}
+	}
+	e.returnLease(lease, lang, leaseResponse(timedOut), err)
+}
+
+func (e *Executor) validateTests(tests []models.TestCase) error {
+	if len(tests) == 0 {
+		return fmt.Errorf("%w: at least one test case is required", ErrInvalidTests)
+	}
+	if len(tests) > MaxTestCases {
+		return fmt.Errorf("%w: more than %d test cases", ErrInvalidTests, MaxTestCases)
+	}
+	for i, test := range tests {
+		if len(test.Input) > e.maxStdinBytes {
+			return fmt.Errorf("%w of %d bytes in test case %d", ErrStdinTooLarge, e.maxStdinBytes, i+1)
+		}
+	}
+	return nil
+}
+
+// validateLimits checks the limits of a judge request. They may lower the
+// language's time and memory limits but never raise them.
+func (e *Executor) validateLimits(req models.JudgeRequest, pool *ContainerPool, lang *language) error {
+	timeLimit := e.runTimeout(lang)
+	switch {
+	case req.TimeLimitMs < 0:
+		return fmt.Errorf("%w: time limit cannot be negative", ErrInvalidLimits)
+	case time.Duration(req.TimeLimitMs)*time.Millisecond > timeLimit:
+		return fmt.Errorf("%w: time limit exceeds the %s limit of %s", ErrInvalidLimits, timeLimit, req.Language)
+	case req.MemoryLimitMB < 0:
+		return fmt.Errorf("%w: memory limit cannot be negative", ErrInvalidLimits)
+	case req.MemoryLimitMB > 0 && req.MemoryLimitMB < MinMemoryMB:
+		return fmt.Errorf("%w: memory limit must be at least %d MB", ErrInvalidLimits, MinMemoryMB)
+	case req.MemoryLimitMB > pool.memoryMB:
+		return fmt.Errorf("%w: memory limit exceeds the %d MB limit of %s", ErrInvalidLimits, pool.memoryMB, req.Language)
+	}
+	return nil
+}
+
+// runVerdict judges a run of a test case by how it ended. Runs that exited
+// normally get no verdict, their output remains to be judged. Only runs the
+// kernel killed for running out of memory exceed the memory limit, other
+// crashes and kills are runtime errors.
+func runVerdict(run models.ExecuteResponse) models.Verdict {
+	switch {
+	case run.Status == models.StatusTimeout:
+		return models.VerdictTimeLimitExceeded
+	case run.Usage.OOMKilled:
+		return models.VerdictMemoryLimitExceeded
+	case run.Status == models.StatusOK:
+		return ""
+	case run.Status == models.StatusOutputLimitExceeded:
+		return models.VerdictOutputLimitExceeded
+	default:
+		return models.VerdictRuntimeError
+	}
+}
+
+// leaseResponse stands in for the response of a job when returning a lease
+// used for several runs.
+func leaseResponse(timedOut bool) models.ExecuteResponse {
+	if timedOut {
+		return models.ExecuteResponse{Status: models.StatusTimeout}
+	}
+	return models.ExecuteResponse{Status: models.StatusOK}
+}
+
+func judgeExecuteRequest(req models.JudgeRequest) models.ExecuteRequest {
+	return models.ExecuteRequest{
+		Language:   req.Language,
+		Code:       req.Code,
+		Files:      req.Files,
+		Entrypoint: req.Entrypoint,
+	}
+}
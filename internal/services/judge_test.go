@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// judgeProgram behaves as its input says: it answers "ok", prints a wrong
+// answer, crashes, runs too long or writes too much.
+func judgeProgram(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+	var input []byte
+	if opts.Stdin != nil {
+		input, _ = io.ReadAll(opts.Stdin)
+	}
+	switch string(input) {
+	case "ac":
+		io.WriteString(opts.Stdout, "ok\n")
+	case "wa":
+		io.WriteString(opts.Stdout, "not ok\n")
+	case "re":
+		io.WriteString(opts.Stderr, "panic\n")
+		return ExecResult{ExitCode: 2}, nil
+	case "tle":
+		time.Sleep(time.Second)
+	case "ole":
+		io.WriteString(opts.Stdout, strings.Repeat("ok\n", 100))
+	}
+	return ExecResult{}, nil
+}
+
+func TestJudgeVerdicts(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(judgeProgram), ExecutorConfig{Timeout: 50 * time.Millisecond, MaxOutputBytes: 64})
+
+	inputs := []string{"ac", "wa", "re", "tle", "ole", "ac"}
+	req := models.JudgeRequest{Language: "python3", Code: "solve()"}
+	for _, input := range inputs {
+		req.TestCases = append(req.TestCases, models.TestCase{Input: input, Expected: "ok\n"})
+	}
+	response, err := e.Judge(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+
+	want := []models.Verdict{
+		models.VerdictAccepted,
+		models.VerdictWrongAnswer,
+		models.VerdictRuntimeError,
+		models.VerdictTimeLimitExceeded,
+		models.VerdictOutputLimitExceeded,
+		models.VerdictAccepted,
+	}
+	if len(response.Tests) != len(want) {
+		t.Fatalf("got %d test results, want %d", len(response.Tests), len(want))
+	}
+	for i, result := range response.Tests {
+		if result.Verdict != want[i] {
+			t.Errorf("test %d (%s): got verdict %s, want %s", i+1, inputs[i], result.Verdict, want[i])
+		}
+	}
+	if response.Verdict != models.VerdictWrongAnswer || response.Passed != 2 || response.Total != len(inputs) {
+		t.Errorf("got verdict %s with %d/%d passed, want WA with 2/%d", response.Verdict, response.Passed, response.Total, len(inputs))
+	}
+}
+
+func TestJudgeStopOnFailure(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(judgeProgram), ExecutorConfig{})
+
+	response, err := e.Judge(context.Background(), models.JudgeRequest{
+		Language:      "python3",
+		Code:          "solve()",
+		StopOnFailure: true,
+		TestCases: []models.TestCase{
+			{Input: "ac", Expected: "ok\n"},
+			{Input: "re", Expected: "ok\n"},
+			{Input: "ac", Expected: "ok\n"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+	if response.Verdict != models.VerdictRuntimeError || len(response.Tests) != 2 {
+		t.Errorf("got verdict %s after %d tests, want RE after 2", response.Verdict, len(response.Tests))
+	}
+}
+
+func TestJudgeCompileError(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "g++" {
+			io.WriteString(opts.Stderr, "main.cpp:1: error\n")
+			return ExecResult{ExitCode: 1}, nil
+		}
+		t.Errorf("ran %v after a failed compilation", opts.Cmd)
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	response, err := e.Judge(context.Background(), models.JudgeRequest{
+		Language:  "cpp",
+		Code:      "int main(",
+		TestCases: []models.TestCase{{Input: "", Expected: ""}},
+	})
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+	if response.Verdict != models.VerdictCompileError || response.CompileOutput != "main.cpp:1: error\n" || len(response.Tests) != 0 {
+		t.Errorf("got verdict %s, compile output %q and %d tests, want CE with the compiler's output and no tests", response.Verdict, response.CompileOutput, len(response.Tests))
+	}
+}
+
+func TestJudgeResetsBetweenTests(t *testing.T) {
+	compiles := 0
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "g++" {
+			compiles++
+			c.Files[workDir+"/main"] = []byte("binary")
+			return ExecResult{}, nil
+		}
+
+		// Leave files behind and report those of earlier tests
+		_, leftover := c.Files["/tmp/state"]
+		_, built := c.Files[workDir+"/main"]
+		c.Files["/tmp/state"] = []byte("test")
+		fmt.Fprintf(opts.Stdout, "leftover=%t built=%t\n", leftover, built)
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	test := models.TestCase{Input: "", Expected: "leftover=false built=true\n"}
+	response, err := e.Judge(context.Background(), models.JudgeRequest{
+		Language:  "cpp",
+		Code:      "int main() {}",
+		TestCases: []models.TestCase{test, test, test},
+	})
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+	if response.Verdict != models.VerdictAccepted {
+		for i, result := range response.Tests {
+			t.Logf("test %d: %q", i+1, result.Stdout)
+		}
+		t.Errorf("got verdict %s, want every test to start from the build alone", response.Verdict)
+	}
+	if compiles != 1 {
+		t.Errorf("compiled %d times, want once", compiles)
+	}
+}
+
+func TestJudgeValidatesTests(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(judgeProgram), ExecutorConfig{})
+
+	tests := map[string][]models.TestCase{
+		"no test cases":       nil,
+		"too many test cases": make([]models.TestCase, MaxTestCases+1),
+	}
+	for name, testCases := range tests {
+		if _, err := e.Judge(context.Background(), models.JudgeRequest{Language: "python3", Code: "solve()", TestCases: testCases}); !errors.Is(err, ErrInvalidTests) {
+			t.Errorf("%s: got error %v, want ErrInvalidTests", name, err)
+		}
+	}
+}
+
+func TestJudgeMemoryLimit(t *testing.T) {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		fmt.Fprintf(opts.Stdout, "%d\n", c.Spec.MemoryMB)
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{MemoryMB: 64})
+
+	for _, limitMB := range []int{0, 32} {
+		wantMB := 64
+		if limitMB > 0 {
+			wantMB = limitMB
+		}
+		response, err := e.Judge(context.Background(), models.JudgeRequest{
+			Language:      "python3",
+			Code:          "print(limit())",
+			MemoryLimitMB: limitMB,
+			TestCases:     []models.TestCase{{Input: "", Expected: fmt.Sprintf("%d\n", wantMB)}},
+		})
+		if err != nil {
+			t.Fatalf("Judge with a limit of %d MB: %v", limitMB, err)
+		}
+		if response.Verdict != models.VerdictAccepted {
+			t.Errorf("limit of %d MB: tests ran with %q MB, want %d", limitMB, response.Tests[0].Stdout, wantMB)
+		}
+	}
+
+	// The limit of the language is restored for the next job
+	for _, id := range rt.Containers() {
+		if c := rt.Container(id); c.Spec.MemoryMB != 64 {
+			t.Errorf("container %s kept a limit of %d MB", id[:12], c.Spec.MemoryMB)
+		}
+	}
+}
+
+func TestJudgeTimeLimit(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(judgeProgram), ExecutorConfig{Timeout: time.Second})
+
+	response, err := e.Judge(context.Background(), models.JudgeRequest{
+		Language:    "python3",
+		Code:        "solve()",
+		TimeLimitMs: 20,
+		TestCases:   []models.TestCase{{Input: "tle", Expected: "ok\n"}},
+	})
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+	if response.Verdict != models.VerdictTimeLimitExceeded {
+		t.Errorf("got verdict %s, want TLE", response.Verdict)
+	}
+}
+
+func TestJudgeValidatesLimits(t *testing.T) {
+	e := newTestExecutor(t, newTestRuntime(judgeProgram), ExecutorConfig{Timeout: time.Second, MemoryMB: 64})
+
+	tests := map[string]models.JudgeRequest{
+		"negative time limit":   {TimeLimitMs: -1},
+		"time limit too high":   {TimeLimitMs: 1001},
+		"negative memory limit": {MemoryLimitMB: -1},
+		"memory limit too low":  {MemoryLimitMB: MinMemoryMB - 1},
+		"memory limit too high": {MemoryLimitMB: 65},
+	}
+	for name, req := range tests {
+		req.Language = "python3"
+		req.Code = "solve()"
+		req.TestCases = []models.TestCase{{Input: "ac", Expected: "ok\n"}}
+		if _, err := e.Judge(context.Background(), req); !errors.Is(err, ErrInvalidLimits) {
+			t.Errorf("%s: got error %v, want ErrInvalidLimits", name, err)
+		}
+	}
+}
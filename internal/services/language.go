@@ -0,0 +1,66 @@
+package services
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// workDir is where code is stored and run inside the sandbox. It is a tmpfs
+// mount that is emptied after every job.
+const workDir = "/sandbox"
+
+// resetScript kills every process of the last job (kill -1 spares only PID 1,
+// the container's sleep, and the shell itself) and deletes its files. The
+// root filesystem of sandboxes is read-only, so the tmpfs mounts and the
+// /dev/shm every container gets are all the places a job can write to.
+const resetScript = "kill -9 -1 2>/dev/null; find " + workDir + " /tmp /dev/shm -mindepth 1 -delete"
+
+// language is a registry entry prepared for the executor.
+type language struct {
+	models.Language
+	namePatterns []*regexp.Regexp
+}
+
+// newLanguage prepares a registry entry. Name patterns have been validated
+// when the registry was loaded.
+func newLanguage(l models.Language) *language {
+	lang := &language{Language: l}
+	for _, pattern := range l.NamePatterns {
+		lang.namePatterns = append(lang.namePatterns, regexp.MustCompile(pattern))
+	}
+	return lang
+}
+
+// recycles reports whether containers are replaced after every job.
+func (l *language) recycles() bool {
+	return l.Isolation == models.IsolationRecycle
+}
+
+// fileName returns the name the code is stored under.
+func (l *language) fileName(code string) string {
+	for _, pattern := range l.namePatterns {
+		if match := pattern.FindStringSubmatch(code); match != nil {
+			return match[1] + path.Ext(l.FileName)
+		}
+	}
+	return l.FileName
+}
+
+// command expands the placeholders of a compile or run command for the
+// source file named fileName.
+func (l *language) command(args []string, fileName string) []string {
+	replacer := strings.NewReplacer(
+		"{{file}}", path.Join(workDir, fileName),
+		"{{name}}", strings.TrimSuffix(fileName, path.Ext(fileName)),
+		"{{dir}}", workDir,
+	)
+
+	cmd := make([]string, len(args))
+	for i, arg := range args {
+		cmd[i] = replacer.Replace(arg)
+	}
+	return cmd
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func TestLanguageFileName(t *testing.T) {
+	java := newLanguage(models.Language{
+		FileName: "Main.java",
+		NamePatterns: []string{
+			`(?m)^\s*public\s+(?:(?:final|abstract|strictfp)\s+)*class\s+([A-Za-z_$][A-Za-z0-9_$]*)`,
+			`(?m)^\s*(?:(?:final|abstract|strictfp)\s+)*class\s+([A-Za-z_$][A-Za-z0-9_$]*)`,
+		},
+	})
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"public class Solution {\n}", "Solution.java"},
+		{"class Helper {}\npublic final class App {\n}", "App.java"},
+		{"abstract class Shape {}\nclass Circle extends Shape {}", "Shape.java"},
+		{"interface Runner {}", "Main.java"},
+	}
+	for _, test := range tests {
+		if got := java.fileName(test.code); got != test.want {
+			t.Errorf("fileName(%q) = %s, want %s", test.code, got, test.want)
+		}
+	}
+}
+
+func TestLanguageCommand(t *testing.T) {
+	lang := newLanguage(models.Language{FileName: "Main.java"})
+	got := lang.command([]string{"java", "-cp", "{{dir}}", "{{name}}", "{{file}}"}, "App.java")
+	want := []string{"java", "-cp", workDir, "App", workDir + "/App.java"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// Labels put on every sandbox container, so containers left behind by a
+// crashed server can be found again.
+const (
+	labelManaged  = "code-engine.managed"
+	labelInstance = "code-engine.instance"
+	labelPool     = "code-engine.pool"
+)
+
+// ReconcileMode selects what happens at startup to sandbox containers left
+// behind by a previous run of the same instance.
+type ReconcileMode string
+
+const (
+	// ReconcileRemove removes leftover containers. It is the default.
+	ReconcileRemove ReconcileMode = "remove"
+	// ReconcileDryRun only logs the containers that would be removed.
+	ReconcileDryRun ReconcileMode = "dry-run"
+	// ReconcileOff leaves leftover containers alone.
+	ReconcileOff ReconcileMode = "off"
+)
+
+// containerLabels returns the labels of a container of the given pool.
+func (e *Executor) containerLabels(pool string) map[string]string {
+	return map[string]string{
+		labelManaged:  "true",
+		labelInstance: e.instance,
+		labelPool:     pool,
+	}
+}
+
+// reconcileOrphans deals with the containers of this instance that already
+// exist before any pool is filled. Nothing can have created them in this run,
+// so they are leftovers of a server that did not shut down cleanly. Their
+// state is unknown, so they are removed rather than adopted.
+func (e *Executor) reconcileOrphans(mode ReconcileMode) {
+	if mode == ReconcileOff {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	orphans, err := e.runtime.List(ctx, map[string]string{
+		labelManaged:  "true",
+		labelInstance: e.instance,
+	})
+	if err != nil {
+		e.logger.Printf("Failed to look for leftover containers of instance %s: %v", e.instance, err)
+		return
+	}
+	if len(orphans) == 0 {
+		return
+	}
+
+	if mode == ReconcileDryRun {
+		for _, orphan := range orphans {
+			e.logger.Printf("Dry run: would remove leftover container %s of %s pool (image: %s, running: %t, started: %s)", orphan.ID[:12], orphan.Labels[labelPool], orphan.Image, orphan.Running, orphan.StartedAt.Format(time.RFC3339))
+		}
+		e.logger.Printf("Dry run: found %d leftover containers of instance %s", len(orphans), e.instance)
+		return
+	}
+
+	removed := 0
+	for _, orphan := range orphans {
+		if err := e.runtime.Remove(ctx, orphan.ID); err != nil {
+			e.logger.Printf("Failed to remove leftover container %s: %v", orphan.ID[:12], err)
+			continue
+		}
+		removed++
+		e.logger.Printf("Removed leftover container %s of %s pool", orphan.ID[:12], orphan.Labels[labelPool])
+	}
+	e.logger.Printf("Removed %d of %d leftover containers of instance %s", removed, len(orphans), e.instance)
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestReconcileOrphans(t *testing.T) {
+	tests := []struct {
+		mode    ReconcileMode
+		removed bool
+	}{
+		{"", true},
+		{ReconcileRemove, true},
+		{ReconcileDryRun, false},
+		{ReconcileOff, false},
+	}
+
+	for _, test := range tests {
+		rt := NewFakeRuntime()
+		orphan, _ := rt.Create(context.Background(), ContainerSpec{
+			Image:  "sandbox-python",
+			Labels: map[string]string{labelManaged: "true", labelInstance: "test", labelPool: "python3"},
+		})
+		other, _ := rt.Create(context.Background(), ContainerSpec{
+			Image:  "sandbox-python",
+			Labels: map[string]string{labelManaged: "true", labelInstance: "other", labelPool: "python3"},
+		})
+		newTestExecutor(t, rt, ExecutorConfig{Instance: "test", Reconcile: test.mode})
+
+		containers := rt.Containers()
+		if slices.Contains(containers, orphan) == test.removed {
+			t.Errorf("mode %q: got leftover container removed %t, want %t", test.mode, !test.removed, test.removed)
+		}
+		if !slices.Contains(containers, other) {
+			t.Errorf("mode %q: removed the container of another instance", test.mode)
+		}
+		for _, id := range containers {
+			if id == orphan || id == other {
+				continue
+			}
+			if labels := rt.Container(id).Spec.Labels; labels[labelInstance] != "test" || labels[labelPool] == "" {
+				t.Errorf("mode %q: pool container has labels %v, want this instance and its pool", test.mode, labels)
+			}
+		}
+	}
+}
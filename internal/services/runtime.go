@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Runtime is the container engine the executor and the container pools run
+// sandboxes on. Implementations must be safe for concurrent use.
+type Runtime interface {
+	// Create creates and starts a container and returns its ID.
+	Create(ctx context.Context, spec ContainerSpec) (string, error)
+	// CopyIn extracts a tar archive into dir inside the container.
+	CopyIn(ctx context.Context, containerID string, dir string, archive io.Reader) error
+	// Exec runs a command inside a running container. A non-zero exit code is
+	// reported in the result, not as an error.
+	Exec(ctx context.Context, containerID string, opts ExecOptions) (ExecResult, error)
+	// CopyOut returns a tar archive with the contents of path inside the container.
+	CopyOut(ctx context.Context, containerID string, path string) (io.ReadCloser, error)
+	// Stop stops the container, killing it if it is still running after timeout.
+	Stop(ctx context.Context, containerID string, timeout time.Duration) error
+	// Remove forcibly removes the container.
+	Remove(ctx context.Context, containerID string) error
+	// SetMemory changes the memory limit of a running container.
+	SetMemory(ctx context.Context, containerID string, memoryMB int) error
+	// Inspect returns the current state of the container.
+	Inspect(ctx context.Context, containerID string) (ContainerInfo, error)
+	// List returns all containers, running or not, carrying every given label.
+	List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error)
+}
+
+// ErrTTYUnsupported is returned by runtimes that cannot attach a terminal.
+var ErrTTYUnsupported = errors.New("terminal sessions are not supported by this runtime")
+
+// TTYCapable is implemented by runtimes that know up front whether they can
+// run commands on a terminal. Runtimes without it are assumed to.
+type TTYCapable interface {
+	SupportsTTY() bool
+}
+
+// ContainerSpec describes a sandbox container. Cmd replaces both the
+// entrypoint and the command of the image.
+type ContainerSpec struct {
+	Image string
+	Cmd   []string
+	CPUs  float64
+	// MemoryMB limits memory and swap together, so that the limit also
+	// holds on hosts with swap.
+	MemoryMB        int
+	NetworkDisabled bool
+	// Tmpfs maps mount points to tmpfs mount options.
+	Tmpfs  map[string]string
+	Labels map[string]string
+	// User runs the processes of the container, as user[:group].
+	User string
+	// ReadOnly mounts the root filesystem read-only.
+	ReadOnly  bool
+	PidsLimit int
+	CapDrop   []string
+	// NoNewPrivileges stops processes from gaining privileges, e.g. through
+	// setuid binaries.
+	NoNewPrivileges bool
+	// SeccompProfile is the path of a seccomp profile file on this host.
+	SeccompProfile string
+	Ulimits        []Ulimit
+}
+
+// ExecOptions describes a command run inside a container. Nil writers
+// discard the corresponding stream and a nil Stdin attaches no input. With
+// Tty the command runs on a terminal whose output, stderr included, is
+// written to Stdout.
+type ExecOptions struct {
+	Cmd     []string
+	WorkDir string
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Tty     bool
+}
+
+type ExecResult struct {
+	ExitCode int
+}
+
+type ContainerInfo struct {
+	ID    string
+	Image string
+	// ImageID identifies the image the container was created from, which
+	// changes when the image is rebuilt under the same name.
+	ImageID   string
+	Running   bool
+	ExitCode  int
+	StartedAt time.Time
+	Labels    map[string]string
+}
+
+// execCopyIn extracts a tar archive by running tar inside the container.
+// Unlike docker cp and the archive endpoint this also reaches tmpfs mounts,
+// which is where sandboxes keep their files.
+func execCopyIn(ctx context.Context, rt Runtime, containerID string, dir string, archive io.Reader) error {
+	var stderr bytes.Buffer
+	result, err := rt.Exec(ctx, containerID, ExecOptions{
+		Cmd:    []string{"tar", "-x", "-C", dir},
+		Stdin:  archive,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy into container %s: %w", containerID[:12], err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to copy into container %s: tar exited with %d: %s", containerID[:12], result.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// execCopyOut archives path by running tar inside the container, see execCopyIn.
+func execCopyOut(ctx context.Context, rt Runtime, containerID string, p string) (io.ReadCloser, error) {
+	var stdout, stderr bytes.Buffer
+	result, err := rt.Exec(ctx, containerID, ExecOptions{
+		Cmd:    []string{"tar", "-c", "-C", path.Dir(p), path.Base(p)},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy from container %s: %w", containerID[:12], err)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to copy from container %s: tar exited with %d: %s", containerID[:12], result.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+	return io.NopCloser(&stdout), nil
+}
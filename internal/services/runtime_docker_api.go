@@ -0,0 +1,428 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultDockerSocket = "/var/run/docker.sock"
+
+	// dockerAPIVersion is the oldest Engine API version providing everything
+	// used below (Docker 20.10).
+	dockerAPIVersion = "v1.41"
+)
+
+// DockerAPIRuntime implements Runtime by talking to the Docker Engine HTTP
+// API over its unix socket, avoiding a docker process per operation.
+type DockerAPIRuntime struct {
+	socket string
+	client *http.Client
+}
+
+// DockerAPIError is a non-successful response from the Engine API.
+type DockerAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *DockerAPIError) Error() string {
+	return fmt.Sprintf("docker api: %s (status %d)", e.Message, e.StatusCode)
+}
+
+func NewDockerAPIRuntime(socket string) *DockerAPIRuntime {
+	if socket == "" {
+		socket = DefaultDockerSocket
+	}
+
+	d := &DockerAPIRuntime{socket: socket}
+	d.client = &http.Client{
+		Transport: &http.Transport{DialContext: d.dial},
+	}
+	return d
+}
+
+func (d *DockerAPIRuntime) Create(ctx context.Context, spec ContainerSpec) (string, error) {
+	hostConfig, err := d.hostConfig(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+	body := map[string]any{
+		"Image":           spec.Image,
+		"Entrypoint":      []string{""},
+		"Cmd":             spec.Cmd,
+		"User":            spec.User,
+		"NetworkDisabled": spec.NetworkDisabled,
+		"Labels":          spec.Labels,
+		"HostConfig":      hostConfig,
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := d.do(ctx, http.MethodPost, "/containers/create", nil, body, &created); err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+
+	if err := d.do(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil, nil); err != nil {
+		d.Remove(context.Background(), created.ID)
+		return "", fmt.Errorf("failed to start container %s: %w", created.ID[:12], err)
+	}
+
+	return created.ID, nil
+}
+
+func (d *DockerAPIRuntime) hostConfig(spec ContainerSpec) (map[string]any, error) {
+	hostConfig := map[string]any{}
+	if spec.NetworkDisabled {
+		hostConfig["NetworkMode"] = "none"
+	}
+	if spec.CPUs > 0 {
+		hostConfig["NanoCpus"] = int64(spec.CPUs * 1e9)
+	}
+	if spec.MemoryMB > 0 {
+		hostConfig["Memory"] = int64(spec.MemoryMB) * 1024 * 1024
+		hostConfig["MemorySwap"] = hostConfig["Memory"]
+	}
+	if len(spec.Tmpfs) > 0 {
+		hostConfig["Tmpfs"] = spec.Tmpfs
+	}
+	if spec.ReadOnly {
+		hostConfig["ReadonlyRootfs"] = true
+	}
+	if spec.PidsLimit > 0 {
+		hostConfig["PidsLimit"] = spec.PidsLimit
+	}
+	if len(spec.CapDrop) > 0 {
+		hostConfig["CapDrop"] = spec.CapDrop
+	}
+
+	var securityOpt []string
+	if spec.NoNewPrivileges {
+		securityOpt = append(securityOpt, "no-new-privileges")
+	}
+	if spec.SeccompProfile != "" {
+		// Unlike the CLI, the API takes the profile itself
+		profile, err := os.ReadFile(spec.SeccompProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, profile); err != nil {
+			return nil, fmt.Errorf("invalid seccomp profile %s: %w", spec.SeccompProfile, err)
+		}
+		securityOpt = append(securityOpt, "seccomp="+compact.String())
+	}
+	if len(securityOpt) > 0 {
+		hostConfig["SecurityOpt"] = securityOpt
+	}
+
+	if len(spec.Ulimits) > 0 {
+		ulimits := make([]map[string]any, len(spec.Ulimits))
+		for i, ulimit := range spec.Ulimits {
+			ulimits[i] = map[string]any{"Name": ulimit.Name, "Soft": ulimit.Soft, "Hard": ulimit.Hard}
+		}
+		hostConfig["Ulimits"] = ulimits
+	}
+	return hostConfig, nil
+}
+
+func (d *DockerAPIRuntime) CopyIn(ctx context.Context, containerID string, dir string, archive io.Reader) error {
+	return execCopyIn(ctx, d, containerID, dir, archive)
+}
+
+func (d *DockerAPIRuntime) Exec(ctx context.Context, containerID string, opts ExecOptions) (ExecResult, error) {
+	body := map[string]any{
+		"AttachStdin":  opts.Stdin != nil,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          opts.Cmd,
+		"WorkingDir":   opts.WorkDir,
+		"Tty":          opts.Tty,
+	}
+	if opts.Tty {
+		body["Env"] = []string{"TERM=xterm"}
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := d.do(ctx, http.MethodPost, "/containers/"+containerID+"/exec", nil, body, &created); err != nil {
+		return ExecResult{ExitCode: -1}, fmt.Errorf("failed to create exec in container %s: %w", containerID[:12], err)
+	}
+
+	if err := d.attachExec(ctx, created.ID, opts); err != nil {
+		if ctx.Err() != nil {
+			return ExecResult{ExitCode: -1}, ctx.Err()
+		}
+		return ExecResult{ExitCode: -1}, fmt.Errorf("failed to exec in container %s: %w", containerID[:12], err)
+	}
+
+	return d.execResult(ctx, created.ID)
+}
+
+// attachExec starts an exec instance on a hijacked connection, feeds it
+// stdin and demultiplexes its output until the process exits.
+func (d *DockerAPIRuntime) attachExec(ctx context.Context, execID string, opts ExecOptions) error {
+	conn, err := d.dial(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	payload, err := json.Marshal(map[string]any{"Detach": false, "Tty": opts.Tty})
+	if err != nil {
+		return err
+	}
+	req, err := d.newRequest(ctx, http.MethodPost, "/exec/"+execID+"/start", nil, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return checkResponse(resp)
+	}
+
+	if opts.Stdin != nil {
+		go func() {
+			io.Copy(conn, opts.Stdin)
+			if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+				cw.CloseWrite()
+			}
+		}()
+	}
+
+	if opts.Tty {
+		// Terminal output is a single raw stream
+		stdout := opts.Stdout
+		if stdout == nil {
+			stdout = io.Discard
+		}
+		_, err := io.Copy(stdout, reader)
+		return err
+	}
+	return demuxStream(reader, opts.Stdout, opts.Stderr)
+}
+
+func (d *DockerAPIRuntime) execResult(ctx context.Context, execID string) (ExecResult, error) {
+	var inspect struct {
+		Running  bool `json:"Running"`
+		ExitCode int  `json:"ExitCode"`
+	}
+	// The exit code can lag slightly behind the end of the output stream.
+	for attempt := 0; attempt < 50; attempt++ {
+		if err := d.do(ctx, http.MethodGet, "/exec/"+execID+"/json", nil, nil, &inspect); err != nil {
+			return ExecResult{ExitCode: -1}, fmt.Errorf("failed to inspect exec %s: %w", execID[:12], err)
+		}
+		if !inspect.Running {
+			return ExecResult{ExitCode: inspect.ExitCode}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return ExecResult{ExitCode: -1}, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return ExecResult{ExitCode: -1}, fmt.Errorf("exec %s did not report an exit code", execID[:12])
+}
+
+func (d *DockerAPIRuntime) CopyOut(ctx context.Context, containerID string, path string) (io.ReadCloser, error) {
+	return execCopyOut(ctx, d, containerID, path)
+}
+
+func (d *DockerAPIRuntime) Stop(ctx context.Context, containerID string, timeout time.Duration) error {
+	query := url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+	if err := d.do(ctx, http.MethodPost, "/containers/"+containerID+"/stop", query, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", containerID[:12], err)
+	}
+	return nil
+}
+
+func (d *DockerAPIRuntime) Remove(ctx context.Context, containerID string) error {
+	query := url.Values{"force": {"1"}}
+	if err := d.do(ctx, http.MethodDelete, "/containers/"+containerID, query, nil, nil); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", containerID[:12], err)
+	}
+	return nil
+}
+
+func (d *DockerAPIRuntime) SetMemory(ctx context.Context, containerID string, memoryMB int) error {
+	limit := int64(memoryMB) * 1024 * 1024
+	body := map[string]any{"Memory": limit, "MemorySwap": limit}
+	if err := d.do(ctx, http.MethodPost, "/containers/"+containerID+"/update", nil, body, nil); err != nil {
+		return fmt.Errorf("failed to update container %s: %w", containerID[:12], err)
+	}
+	return nil
+}
+
+func (d *DockerAPIRuntime) Inspect(ctx context.Context, containerID string) (ContainerInfo, error) {
+	var container dockerContainerJSON
+	if err := d.do(ctx, http.MethodGet, "/containers/"+containerID+"/json", nil, nil, &container); err != nil {
+		return ContainerInfo{}, fmt.Errorf("failed to inspect container %s: %w", containerID[:12], err)
+	}
+	return container.info(), nil
+}
+
+func (d *DockerAPIRuntime) List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error) {
+	filters := map[string][]string{"label": {}}
+	for key, value := range labels {
+		filters["label"] = append(filters["label"], key+"="+value)
+	}
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []struct {
+		ID      string            `json:"Id"`
+		Image   string            `json:"Image"`
+		ImageID string            `json:"ImageID"`
+		State   string            `json:"State"`
+		Created int64             `json:"Created"`
+		Labels  map[string]string `json:"Labels"`
+	}
+	query := url.Values{"all": {"1"}, "filters": {string(encoded)}}
+	if err := d.do(ctx, http.MethodGet, "/containers/json", query, nil, &containers); err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	infos := make([]ContainerInfo, len(containers))
+	for i, c := range containers {
+		// The list endpoint only reports the creation time, which is close
+		// enough to the start of a sandbox.
+		infos[i] = ContainerInfo{
+			ID:        c.ID,
+			Image:     c.Image,
+			ImageID:   c.ImageID,
+			Running:   c.State == "running",
+			StartedAt: time.Unix(c.Created, 0),
+			Labels:    c.Labels,
+		}
+	}
+	return infos, nil
+}
+
+func (d *DockerAPIRuntime) dial(ctx context.Context, _, _ string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", d.socket)
+}
+
+func (d *DockerAPIRuntime) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := url.URL{Scheme: "http", Host: "docker", Path: "/" + dockerAPIVersion + path, RawQuery: query.Encode()}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given.
+func (d *DockerAPIRuntime) do(ctx context.Context, method, path string, query url.Values, in any, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := d.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkResponse turns an error status into a DockerAPIError. 304 Not
+// Modified (e.g. stopping a stopped container) counts as success.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		body.Message = string(bytes.TrimSpace(data))
+	}
+	return &DockerAPIError{StatusCode: resp.StatusCode, Message: body.Message}
+}
+
+// demuxStream splits the multiplexed stdout/stderr stream of a non-TTY
+// attach. Every frame is an 8 byte header (stream type, 3 bytes padding,
+// big-endian payload size) followed by the payload.
+func demuxStream(r io.Reader, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var dst io.Writer
+		switch header[0] {
+		case 0, 1:
+			dst = stdout
+		case 2:
+			dst = stderr
+		default:
+			return fmt.Errorf("unexpected stream type %d", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(dst, r, size); err != nil {
+			return err
+		}
+	}
+}
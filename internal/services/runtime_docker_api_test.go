@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testContainerID is the ID of the only container of the stand-in engine.
+const testContainerID = "c0ffee000000000000000000000000000000000000000000000000000000cafe"
+
+// standInEngine answers the Engine API requests of DockerAPIRuntime on a
+// unix socket. Its exec instances echo stdin to stdout, write "warning\n"
+// to stderr and exit with the number of their arguments.
+type standInEngine struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	created map[string]any
+	started []string
+	execs   map[string][]string
+	polls   int
+}
+
+// newStandInEngine starts the engine and returns a runtime talking to it.
+func newStandInEngine(t *testing.T) (*standInEngine, *DockerAPIRuntime) {
+	t.Helper()
+	engine := &standInEngine{t: t, execs: make(map[string][]string)}
+
+	mux := http.NewServeMux()
+	prefix := "/" + dockerAPIVersion
+	mux.HandleFunc("POST "+prefix+"/containers/create", engine.create)
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/start", engine.start)
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/exec", engine.createExec)
+	mux.HandleFunc("POST "+prefix+"/exec/{id}/start", engine.startExec)
+	mux.HandleFunc("GET "+prefix+"/exec/{id}/json", engine.inspectExec)
+	mux.HandleFunc("GET "+prefix+"/containers/{id}/json", engine.inspect)
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "docker.sock"))
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return engine, NewDockerAPIRuntime(listener.Addr().String())
+}
+
+func (s *standInEngine) create(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.created = body
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"Id": testContainerID})
+}
+
+func (s *standInEngine) start(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.started = append(s.started, r.PathValue("id"))
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *standInEngine) createExec(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") != testContainerID {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No such container: " + r.PathValue("id")})
+		return
+	}
+	var body struct {
+		Cmd []string `json:"Cmd"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	id := strings.Repeat("e", 63) + string(rune('0'+len(s.execs)))
+	s.execs[id] = body.Cmd
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"Id": id})
+}
+
+// startExec takes over the connection like the engine does and streams the
+// output of the exec in multiplexed frames.
+func (s *standInEngine) startExec(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	if r.Header.Get("Upgrade") != "tcp" {
+		http.Error(w, "expected an upgrade", http.StatusBadRequest)
+		return
+	}
+
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		s.t.Errorf("failed to hijack: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	buf.Flush()
+
+	stdin, err := io.ReadAll(buf)
+	if err != nil {
+		s.t.Errorf("failed to read stdin: %v", err)
+		return
+	}
+	writeFrame(conn, 1, stdin)
+	writeFrame(conn, 2, []byte("warning\n"))
+}
+
+func writeFrame(w io.Writer, stream byte, payload []byte) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	w.Write(append(header, payload...))
+}
+
+// inspectExec reports the exec as running on the first poll, like the
+// engine may right after the output ends.
+func (s *standInEngine) inspectExec(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	cmd, ok := s.execs[r.PathValue("id")]
+	s.polls++
+	running := s.polls == 1
+	s.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"Running": running, "ExitCode": len(cmd) - 1})
+}
+
+func (s *standInEngine) inspect(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"Id":     r.PathValue("id"),
+		"Config": map[string]any{"Image": "sandbox-python", "Labels": map[string]string{labelPool: "python3"}},
+		"State":  map[string]any{"Running": false, "ExitCode": 137, "StartedAt": "2024-05-01T12:00:00Z"},
+	})
+}
+
+func TestDockerAPIRuntimeCreate(t *testing.T) {
+	engine, d := newStandInEngine(t)
+
+	spec := ContainerSpec{
+		Image:           "sandbox-python",
+		Cmd:             []string{"sleep", "3600"},
+		CPUs:            0.5,
+		MemoryMB:        64,
+		NetworkDisabled: true,
+		Labels:          map[string]string{labelPool: "python3"},
+	}
+	SandboxConfig{}.apply(&spec)
+	id, err := d.Create(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if id != testContainerID {
+		t.Errorf("got container %s, want %s", id, testContainerID)
+	}
+	if !slices.Equal(engine.started, []string{testContainerID}) {
+		t.Errorf("started containers %v, want the created one", engine.started)
+	}
+
+	if got := engine.created["Image"]; got != "sandbox-python" {
+		t.Errorf("Image = %v, want sandbox-python", got)
+	}
+	if got := engine.created["NetworkDisabled"]; got != true {
+		t.Errorf("NetworkDisabled = %v, want true", got)
+	}
+	if labels, _ := engine.created["Labels"].(map[string]any); labels[labelPool] != "python3" {
+		t.Errorf("Labels = %v, want the pool label", labels)
+	}
+	hostConfig, _ := engine.created["HostConfig"].(map[string]any)
+	checks := map[string]any{
+		"NetworkMode":    "none",
+		"NanoCpus":       5e8,
+		"Memory":         float64(64 << 20),
+		"MemorySwap":     float64(64 << 20),
+		"ReadonlyRootfs": true,
+		"PidsLimit":      float64(DefaultPidsLimit),
+	}
+	for key, want := range checks {
+		if got := hostConfig[key]; got != want {
+			t.Errorf("HostConfig.%s = %v, want %v", key, got, want)
+		}
+	}
+	lists := map[string]string{
+		"CapDrop":     "[ALL]",
+		"SecurityOpt": "[no-new-privileges]",
+		"Ulimits":     "[map[Hard:3.3554432e+07 Name:fsize Soft:3.3554432e+07] map[Hard:1024 Name:nofile Soft:1024]]",
+		"Tmpfs":       "map[/sandbox:rw,exec,mode=1777,size=64m /tmp:rw,exec,mode=1777,size=64m]",
+	}
+	for key, want := range lists {
+		if got := fmt.Sprint(hostConfig[key]); got != want {
+			t.Errorf("HostConfig.%s = %s, want %s", key, got, want)
+		}
+	}
+	if got := engine.created["User"]; got != DefaultSandboxUser {
+		t.Errorf("User = %v, want %s", got, DefaultSandboxUser)
+	}
+}
+
+func TestDockerAPIRuntimeExec(t *testing.T) {
+	engine, d := newStandInEngine(t)
+
+	var stdout, stderr bytes.Buffer
+	result, err := d.Exec(context.Background(), testContainerID, ExecOptions{
+		Cmd:    []string{"cat", "-", "-"},
+		Stdin:  strings.NewReader("hello\n"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if stdout.String() != "hello\n" || stderr.String() != "warning\n" {
+		t.Errorf("got stdout %q and stderr %q, want %q and %q", stdout.String(), stderr.String(), "hello\n", "warning\n")
+	}
+	if result.ExitCode != 2 {
+		t.Errorf("got exit code %d, want 2", result.ExitCode)
+	}
+	if engine.polls < 2 {
+		t.Errorf("exit code taken after %d polls, want it to wait for the exec to stop", engine.polls)
+	}
+}
+
+func TestDockerAPIRuntimeErrors(t *testing.T) {
+	_, d := newStandInEngine(t)
+
+	_, err := d.Exec(context.Background(), strings.Repeat("0", 64), ExecOptions{Cmd: []string{"true"}})
+	var apiErr *DockerAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || !strings.Contains(apiErr.Message, "No such container") {
+		t.Errorf("Exec in a missing container: got %v, want a 404 DockerAPIError with the engine's message", err)
+	}
+}
+
+func TestDockerAPIRuntimeInspect(t *testing.T) {
+	_, d := newStandInEngine(t)
+
+	info, err := d.Inspect(context.Background(), testContainerID)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	want := ContainerInfo{
+		ID:        testContainerID,
+		Image:     "sandbox-python",
+		ExitCode:  137,
+		StartedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if info.ID != want.ID || info.Image != want.Image || info.Running || info.ExitCode != want.ExitCode || !info.StartedAt.Equal(want.StartedAt) || info.Labels[labelPool] != "python3" {
+		t.Errorf("got %+v, want %+v with the pool label", info, want)
+	}
+}
+
+func TestDemuxStream(t *testing.T) {
+	var stream bytes.Buffer
+	writeFrame(&stream, 1, []byte("out "))
+	writeFrame(&stream, 2, []byte("err"))
+	writeFrame(&stream, 0, []byte("put"))
+	writeFrame(&stream, 1, nil)
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxStream(&stream, &stdout, &stderr); err != nil {
+		t.Fatalf("demuxStream: %v", err)
+	}
+	if stdout.String() != "out put" || stderr.String() != "err" {
+		t.Errorf("got stdout %q and stderr %q, want %q and %q", stdout.String(), stderr.String(), "out put", "err")
+	}
+
+	// Streams without a writer are discarded
+	stream.Reset()
+	writeFrame(&stream, 2, []byte("dropped"))
+	if err := demuxStream(&stream, &stdout, nil); err != nil {
+		t.Errorf("demuxStream without stderr: %v", err)
+	}
+
+	stream.Reset()
+	writeFrame(&stream, 3, []byte("?"))
+	if err := demuxStream(&stream, io.Discard, io.Discard); err == nil {
+		t.Error("demuxStream accepted an unknown stream type")
+	}
+
+	stream.Reset()
+	writeFrame(&stream, 1, []byte("cut short"))
+	stream.Truncate(stream.Len() - 3)
+	if err := demuxStream(&stream, io.Discard, io.Discard); !errors.Is(err, io.EOF) {
+		t.Errorf("demuxStream of a truncated frame: got %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DockerCLIRuntime implements Runtime by shelling out to the docker CLI.
+type DockerCLIRuntime struct {
+	binary string
+}
+
+func NewDockerCLIRuntime() *DockerCLIRuntime {
+	return &DockerCLIRuntime{binary: "docker"}
+}
+
+func (d *DockerCLIRuntime) Create(ctx context.Context, spec ContainerSpec) (string, error) {
+	args := []string{"run", "-d"}
+	if spec.NetworkDisabled {
+		args = append(args, "--net=none")
+	}
+	if spec.CPUs > 0 {
+		args = append(args, "--cpus="+strconv.FormatFloat(spec.CPUs, 'f', -1, 64))
+	}
+	if spec.MemoryMB > 0 {
+		limit := fmt.Sprintf("%dm", spec.MemoryMB)
+		args = append(args, "--memory="+limit, "--memory-swap="+limit)
+	}
+	for mountPoint, options := range spec.Tmpfs {
+		args = append(args, "--tmpfs", mountPoint+":"+options)
+	}
+	if spec.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	for key, value := range spec.Labels {
+		args = append(args, "--label", key+"="+value)
+	}
+	if spec.User != "" {
+		args = append(args, "--user="+spec.User)
+	}
+	if spec.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	if spec.PidsLimit > 0 {
+		args = append(args, "--pids-limit="+strconv.Itoa(spec.PidsLimit))
+	}
+	for _, capability := range spec.CapDrop {
+		args = append(args, "--cap-drop="+capability)
+	}
+	if spec.NoNewPrivileges {
+		args = append(args, "--security-opt=no-new-privileges")
+	}
+	if spec.SeccompProfile != "" {
+		// The CLI reads the profile itself
+		args = append(args, "--security-opt=seccomp="+spec.SeccompProfile)
+	}
+	for _, ulimit := range spec.Ulimits {
+		args = append(args, fmt.Sprintf("--ulimit=%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+	args = append(args, "--entrypoint=", spec.Image)
+	args = append(args, spec.Cmd...)
+
+	output, err := d.command(ctx, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", cliError(err))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (d *DockerCLIRuntime) CopyIn(ctx context.Context, containerID string, dir string, archive io.Reader) error {
+	return execCopyIn(ctx, d, containerID, dir, archive)
+}
+
+// SupportsTTY reports false: docker exec -t insists on being run from a
+// terminal itself.
+func (d *DockerCLIRuntime) SupportsTTY() bool {
+	return false
+}
+
+func (d *DockerCLIRuntime) Exec(ctx context.Context, containerID string, opts ExecOptions) (ExecResult, error) {
+	// docker exec -t insists on being run from a terminal itself
+	if opts.Tty {
+		return ExecResult{ExitCode: -1}, ErrTTYUnsupported
+	}
+
+	args := []string{"exec"}
+	if opts.Stdin != nil {
+		args = append(args, "-i")
+	}
+	if opts.WorkDir != "" {
+		args = append(args, "-w", opts.WorkDir)
+	}
+	args = append(args, containerID)
+	args = append(args, opts.Cmd...)
+
+	cmd := d.command(ctx, args...)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return ExecResult{ExitCode: -1}, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return ExecResult{ExitCode: exitErr.ExitCode()}, nil
+	}
+	if err != nil {
+		return ExecResult{ExitCode: -1}, fmt.Errorf("failed to exec in container %s: %w", containerID[:12], err)
+	}
+
+	return ExecResult{}, nil
+}
+
+func (d *DockerCLIRuntime) CopyOut(ctx context.Context, containerID string, path string) (io.ReadCloser, error) {
+	return execCopyOut(ctx, d, containerID, path)
+}
+
+func (d *DockerCLIRuntime) Stop(ctx context.Context, containerID string, timeout time.Duration) error {
+	seconds := strconv.Itoa(int(timeout.Seconds()))
+	if _, err := d.command(ctx, "stop", "-t", seconds, containerID).Output(); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", containerID[:12], cliError(err))
+	}
+	return nil
+}
+
+func (d *DockerCLIRuntime) Remove(ctx context.Context, containerID string) error {
+	if _, err := d.command(ctx, "rm", "-f", containerID).Output(); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", containerID[:12], cliError(err))
+	}
+	return nil
+}
+
+func (d *DockerCLIRuntime) SetMemory(ctx context.Context, containerID string, memoryMB int) error {
+	limit := fmt.Sprintf("%dm", memoryMB)
+	if _, err := d.command(ctx, "update", "--memory="+limit, "--memory-swap="+limit, containerID).Output(); err != nil {
+		return fmt.Errorf("failed to update container %s: %w", containerID[:12], cliError(err))
+	}
+	return nil
+}
+
+func (d *DockerCLIRuntime) Inspect(ctx context.Context, containerID string) (ContainerInfo, error) {
+	output, err := d.command(ctx, "inspect", containerID).Output()
+	if err != nil {
+		return ContainerInfo{}, fmt.Errorf("failed to inspect container %s: %w", containerID[:12], cliError(err))
+	}
+
+	var containers []dockerContainerJSON
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return ContainerInfo{}, fmt.Errorf("failed to decode inspect output: %w", err)
+	}
+	if len(containers) == 0 {
+		return ContainerInfo{}, fmt.Errorf("container %s not found", containerID[:12])
+	}
+
+	return containers[0].info(), nil
+}
+
+func (d *DockerCLIRuntime) List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error) {
+	args := []string{"ps", "-a", "-q", "--no-trunc"}
+	for key, value := range labels {
+		args = append(args, "--filter", "label="+key+"="+value)
+	}
+	output, err := d.command(ctx, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", cliError(err))
+	}
+
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	output, err = d.command(ctx, append([]string{"inspect"}, ids...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect containers: %w", cliError(err))
+	}
+
+	var containers []dockerContainerJSON
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("failed to decode inspect output: %w", err)
+	}
+
+	infos := make([]ContainerInfo, len(containers))
+	for i, container := range containers {
+		infos[i] = container.info()
+	}
+	return infos, nil
+}
+
+func (d *DockerCLIRuntime) command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, d.binary, args...)
+}
+
+// dockerContainerJSON is the subset of the container inspect document
+// returned by both `docker inspect` and the Engine API.
+type dockerContainerJSON struct {
+	ID      string `json:"Id"`
+	ImageID string `json:"Image"`
+	Config  struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	State struct {
+		Running   bool      `json:"Running"`
+		ExitCode  int       `json:"ExitCode"`
+		StartedAt time.Time `json:"StartedAt"`
+	} `json:"State"`
+}
+
+func (c dockerContainerJSON) info() ContainerInfo {
+	return ContainerInfo{
+		ID:        c.ID,
+		Image:     c.Config.Image,
+		ImageID:   c.ImageID,
+		Running:   c.State.Running,
+		ExitCode:  c.State.ExitCode,
+		StartedAt: c.State.StartedAt,
+		Labels:    c.Config.Labels,
+	}
+}
+
+// cliError adds the stderr of a failed docker invocation to its error.
+func cliError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeRuntime is an in-memory Runtime for tests. Containers are plain
+// records, files copied in are kept per container and every Exec is answered
+// by ExecFunc, so executor and pool behavior can be driven deterministically.
+type FakeRuntime struct {
+	// ExecFunc answers Exec calls. When nil, every command succeeds without output.
+	ExecFunc func(c *FakeContainer, opts ExecOptions) (ExecResult, error)
+	// CreateErr, when set, is returned by every Create call.
+	CreateErr error
+	// NoTTY makes the runtime report that it cannot run commands on a terminal.
+	NoTTY bool
+	// ImageIDs maps image names to the IDs containers report; images not
+	// in it are identified by their name.
+	ImageIDs map[string]string
+
+	mu         sync.Mutex
+	nextID     int
+	containers map[string]*FakeContainer
+}
+
+// FakeContainer is the state FakeRuntime keeps for a container.
+type FakeContainer struct {
+	ID      string
+	Spec    ContainerSpec
+	ImageID string
+	Running bool
+	Files   map[string][]byte
+	Execs   [][]string
+	Started time.Time
+}
+
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{containers: make(map[string]*FakeContainer)}
+}
+
+func (f *FakeRuntime) Create(ctx context.Context, spec ContainerSpec) (string, error) {
+	if f.CreateErr != nil {
+		return "", f.CreateErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	id := fmt.Sprintf("%012x", f.nextID) + strings.Repeat("0", 52)
+	imageID, ok := f.ImageIDs[spec.Image]
+	if !ok {
+		imageID = spec.Image
+	}
+	f.containers[id] = &FakeContainer{
+		ID:      id,
+		Spec:    spec,
+		ImageID: imageID,
+		Running: true,
+		Files:   make(map[string][]byte),
+		Started: time.Unix(int64(f.nextID), 0),
+	}
+	return id, nil
+}
+
+func (f *FakeRuntime) CopyIn(ctx context.Context, containerID string, dir string, archive io.Reader) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.running(containerID)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		c.Files[path.Join(dir, hdr.Name)] = data
+	}
+}
+
+func (f *FakeRuntime) Exec(ctx context.Context, containerID string, opts ExecOptions) (ExecResult, error) {
+	f.mu.Lock()
+	c, err := f.running(containerID)
+	if err != nil {
+		f.mu.Unlock()
+		return ExecResult{ExitCode: -1}, err
+	}
+	c.Execs = append(c.Execs, opts.Cmd)
+	f.mu.Unlock()
+
+	if f.ExecFunc == nil {
+		return ExecResult{}, nil
+	}
+
+	// Like the real runtimes, give up on the command when ctx is done
+	type execReturn struct {
+		result ExecResult
+		err    error
+	}
+	done := make(chan execReturn, 1)
+	go func() {
+		result, err := f.ExecFunc(c, opts)
+		done <- execReturn{result, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ExecResult{ExitCode: -1}, ctx.Err()
+	case r := <-done:
+		return r.result, r.err
+	}
+}
+
+func (f *FakeRuntime) SupportsTTY() bool {
+	return !f.NoTTY
+}
+
+func (f *FakeRuntime) CopyOut(ctx context.Context, containerID string, p string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return nil, fmt.Errorf("container %s not found", containerID)
+	}
+
+	var names []string
+	for name := range c.Files {
+		if name == p || strings.HasPrefix(name, strings.TrimSuffix(p, "/")+"/") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no such file in container %s: %s", containerID[:12], p)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		rel := strings.TrimPrefix(name, path.Dir(p)+"/")
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: int64(len(c.Files[name]))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(c.Files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func (f *FakeRuntime) Stop(ctx context.Context, containerID string, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return fmt.Errorf("container %s not found", containerID)
+	}
+	c.Running = false
+	return nil
+}
+
+func (f *FakeRuntime) Remove(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.containers[containerID]; !ok {
+		return fmt.Errorf("container %s not found", containerID)
+	}
+	delete(f.containers, containerID)
+	return nil
+}
+
+func (f *FakeRuntime) SetMemory(ctx context.Context, containerID string, memoryMB int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.running(containerID)
+	if err != nil {
+		return err
+	}
+	c.Spec.MemoryMB = memoryMB
+	return nil
+}
+
+func (f *FakeRuntime) Inspect(ctx context.Context, containerID string) (ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return ContainerInfo{}, fmt.Errorf("container %s not found", containerID)
+	}
+	return c.info(), nil
+}
+
+func (f *FakeRuntime) List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var infos []ContainerInfo
+containers:
+	for _, c := range f.containers {
+		for key, value := range labels {
+			if c.Spec.Labels[key] != value {
+				continue containers
+			}
+		}
+		infos = append(infos, c.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, nil
+}
+
+// Container returns the state of a container, or nil if it does not exist.
+func (f *FakeRuntime) Container(containerID string) *FakeContainer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.containers[containerID]
+}
+
+// Containers returns the IDs of all existing containers in creation order.
+func (f *FakeRuntime) Containers() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]string, 0, len(f.containers))
+	for id := range f.containers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (c *FakeContainer) info() ContainerInfo {
+	return ContainerInfo{
+		ID:        c.ID,
+		Image:     c.Spec.Image,
+		ImageID:   c.ImageID,
+		Running:   c.Running,
+		StartedAt: c.Started,
+		Labels:    c.Spec.Labels,
+	}
+}
+
+// running returns the container if it exists and is running. f.mu must be held.
+func (f *FakeRuntime) running(containerID string) (*FakeContainer, error) {
+	c, ok := f.containers[containerID]
+	if !ok {
+		return nil, fmt.Errorf("container %s not found", containerID)
+	}
+	if !c.Running {
+		return nil, fmt.Errorf("container %s is not running", containerID[:12])
+	}
+	return c, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import "fmt"
+
+// Defaults of the sandbox profile, see SandboxConfig.
+const (
+	DefaultTmpfsMB       = 64
+	DefaultSandboxUser   = "65534:65534" // nobody
+	DefaultPidsLimit     = 128
+	DefaultMaxFileSizeMB = 32
+	DefaultMaxOpenFiles  = 1024
+)
+
+// SandboxConfig is the security profile of sandbox containers. Zero values
+// are the hardened defaults; every protection has to be turned off
+// explicitly.
+type SandboxConfig struct {
+	// User runs sandbox processes, as user[:group] (default
+	// DefaultSandboxUser). Set it to "root" to run code as root.
+	User string
+	// WritableRoot lifts the read-only root filesystem, which otherwise
+	// leaves code only the tmpfs mounts at workDir and /tmp to write to.
+	WritableRoot bool
+	// WorkDirMB and TmpMB cap the tmpfs mounts (default DefaultTmpfsMB).
+	WorkDirMB int
+	TmpMB     int
+	// PidsLimit caps processes and threads, against fork bombs (default
+	// DefaultPidsLimit). Negative leaves it to the container engine.
+	PidsLimit int
+	// KeepCapabilities keeps the engine's default capabilities instead of
+	// dropping all of them.
+	KeepCapabilities bool
+	// AllowNewPrivileges lets setuid binaries gain privileges.
+	AllowNewPrivileges bool
+	// MaxFileSizeMB and MaxOpenFiles set the fsize and nofile ulimits
+	// (default DefaultMaxFileSizeMB and DefaultMaxOpenFiles). Negative
+	// values leave them to the container engine.
+	MaxFileSizeMB int
+	MaxOpenFiles  int
+	// SeccompProfile is the path of a seccomp profile file on this host
+	// replacing the engine's default profile.
+	SeccompProfile string
+}
+
+// readOnly reports whether the root filesystem of sandboxes is read-only.
+func (c SandboxConfig) readOnly() bool {
+	return !c.WritableRoot
+}
+
+// Ulimit is a resource limit of the processes of a container.
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// apply adds the profile to the spec of a sandbox container.
+func (c SandboxConfig) apply(spec *ContainerSpec) {
+	spec.Tmpfs = map[string]string{workDir: tmpfsOptions(c.WorkDirMB)}
+	if c.readOnly() {
+		spec.Tmpfs["/tmp"] = tmpfsOptions(c.TmpMB)
+	}
+
+	spec.User = c.User
+	if spec.User == "" {
+		spec.User = DefaultSandboxUser
+	}
+	spec.ReadOnly = c.readOnly()
+	spec.PidsLimit = orDefault(c.PidsLimit, DefaultPidsLimit)
+	if !c.KeepCapabilities {
+		spec.CapDrop = []string{"ALL"}
+	}
+	spec.NoNewPrivileges = !c.AllowNewPrivileges
+	spec.SeccompProfile = c.SeccompProfile
+
+	if sizeMB := orDefault(c.MaxFileSizeMB, DefaultMaxFileSizeMB); sizeMB > 0 {
+		size := int64(sizeMB) << 20
+		spec.Ulimits = append(spec.Ulimits, Ulimit{Name: "fsize", Soft: size, Hard: size})
+	}
+	if n := int64(orDefault(c.MaxOpenFiles, DefaultMaxOpenFiles)); n > 0 {
+		spec.Ulimits = append(spec.Ulimits, Ulimit{Name: "nofile", Soft: n, Hard: n})
+	}
+}
+
+// orDefault returns value, or def if value is zero. Negative values turn a
+// limit off and are returned as 0.
+func orDefault(value int, def int) int {
+	switch {
+	case value == 0:
+		return def
+	case value < 0:
+		return 0
+	default:
+		return value
+	}
+}
+
+// tmpfsOptions returns the mount options of a sandbox tmpfs. Code may run
+// from it and every user may write to it, whatever user the sandbox runs as.
+func tmpfsOptions(sizeMB int) string {
+	if sizeMB <= 0 {
+		sizeMB = DefaultTmpfsMB
+	}
+	return fmt.Sprintf("rw,exec,mode=1777,size=%dm", sizeMB)
+}
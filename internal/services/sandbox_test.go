@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func TestSandboxConfigDefaults(t *testing.T) {
+	var spec ContainerSpec
+	SandboxConfig{}.apply(&spec)
+
+	if spec.User != DefaultSandboxUser || !spec.ReadOnly || spec.PidsLimit != DefaultPidsLimit || !spec.NoNewPrivileges {
+		t.Errorf("got user %q, read-only %t, pids limit %d and no new privileges %t, want the hardened defaults", spec.User, spec.ReadOnly, spec.PidsLimit, spec.NoNewPrivileges)
+	}
+	if !slices.Equal(spec.CapDrop, []string{"ALL"}) {
+		t.Errorf("got dropped capabilities %v, want ALL", spec.CapDrop)
+	}
+	want := []Ulimit{
+		{Name: "fsize", Soft: DefaultMaxFileSizeMB << 20, Hard: DefaultMaxFileSizeMB << 20},
+		{Name: "nofile", Soft: DefaultMaxOpenFiles, Hard: DefaultMaxOpenFiles},
+	}
+	if !slices.Equal(spec.Ulimits, want) {
+		t.Errorf("got ulimits %v, want %v", spec.Ulimits, want)
+	}
+	if len(spec.Tmpfs) != 2 || spec.Tmpfs[workDir] != tmpfsOptions(0) || spec.Tmpfs["/tmp"] != tmpfsOptions(0) {
+		t.Errorf("got tmpfs mounts %v, want %s and /tmp", spec.Tmpfs, workDir)
+	}
+}
+
+func TestSandboxConfigOptOut(t *testing.T) {
+	var spec ContainerSpec
+	SandboxConfig{
+		User:               "root",
+		WritableRoot:       true,
+		WorkDirMB:          16,
+		PidsLimit:          -1,
+		KeepCapabilities:   true,
+		AllowNewPrivileges: true,
+		MaxFileSizeMB:      -1,
+		MaxOpenFiles:       -1,
+	}.apply(&spec)
+
+	if spec.User != "root" || spec.ReadOnly || spec.PidsLimit != 0 || spec.NoNewPrivileges || spec.CapDrop != nil || spec.Ulimits != nil {
+		t.Errorf("got %+v, want every protection turned off", spec)
+	}
+	if len(spec.Tmpfs) != 1 || spec.Tmpfs[workDir] != "rw,exec,mode=1777,size=16m" {
+		t.Errorf("got tmpfs mounts %v, want a 16 MB %s only", spec.Tmpfs, workDir)
+	}
+}
+
+func TestWritableRootRecyclesContainers(t *testing.T) {
+	var seen []string
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		seen = append(seen, c.ID)
+		return ExecResult{}, nil
+	})
+	e := newTestExecutor(t, rt, ExecutorConfig{Sandbox: SandboxConfig{WritableRoot: true}})
+
+	for range 2 {
+		if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	if len(seen) != 2 || seen[0] == seen[1] {
+		t.Errorf("runs used containers %v, want a new one for every run on a writable root filesystem", seen)
+	}
+}
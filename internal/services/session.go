@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// Session limits used when SessionConfig leaves them unset.
+const (
+	DefaultSessionIdleTimeout = 5 * time.Minute
+	DefaultSessionMaxDuration = 30 * time.Minute
+)
+
+var (
+	ErrNoRepl          = errors.New("language has no REPL")
+	ErrTooManySessions = errors.New("too many sessions are running")
+)
+
+// errSessionIdle is the cancellation cause of sessions that were idle too long.
+var errSessionIdle = errors.New("session idle timeout")
+
+// SessionConfig limits interactive sessions.
+type SessionConfig struct {
+	// IdleTimeout ends a session without input or output for that long.
+	IdleTimeout time.Duration
+	// MaxDuration ends a session regardless of activity.
+	MaxDuration time.Duration
+	// MaxConcurrent limits the sessions running at the same time, across
+	// all languages, so that sessions cannot hold every container while
+	// executions wait. It defaults to half the pool size of a language.
+	MaxConcurrent int
+}
+
+// SupportsSessions reports whether the runtime can run sessions on a terminal.
+func (e *Executor) SupportsSessions() bool {
+	rt, ok := e.runtime.(TTYCapable)
+	return !ok || rt.SupportsTTY()
+}
+
+// RunSession runs an interactive program on a terminal, in a container that
+// is leased for the whole session: the given code, compiled first if
+// needed, or else the language's REPL. Keystrokes are read from input and
+// terminal output is written to output until the program exits or a
+// session timeout ends it, which is reported as StatusTimeout. The response
+// carries no output, all of it has been written to output.
+func (e *Executor) RunSession(ctx context.Context, req models.SessionRequest, input io.Reader, output io.Writer) (models.ExecuteResponse, error) {
+	pool, lang, err := e.lookup(sessionExecuteRequest(req))
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+	if req.Code == "" && req.Entrypoint == "" && len(lang.Repl) == 0 {
+		return models.ExecuteResponse{}, fmt.Errorf("%w: %s", ErrNoRepl, req.Language)
+	}
+	// Fail before a container is leased, a failed terminal exec discards it
+	if !e.SupportsSessions() {
+		return models.ExecuteResponse{}, ErrTTYUnsupported
+	}
+
+	select {
+	case e.sessionSlots <- struct{}{}:
+		defer func() { <-e.sessionSlots }()
+	default:
+		return models.ExecuteResponse{}, fmt.Errorf("%w, at most %d", ErrTooManySessions, cap(e.sessionSlots))
+	}
+
+	start := time.Now()
+
+	acquireCtx, cancel := context.WithTimeout(ctx, e.acquireTimeout)
+	lease, err := pool.Acquire(acquireCtx)
+	cancel()
+	if err != nil {
+		return models.ExecuteResponse{}, fmt.Errorf("failed to get container from pool: %w", err)
+	}
+
+	waited := time.Since(start)
+	e.logger.Printf("Started %s session in container %s", req.Language, lease.ContainerID[:12])
+
+	ctx, cancelIdle := context.WithCancelCause(ctx)
+	defer cancelIdle(nil)
+	ctx, cancelMax := context.WithTimeout(ctx, e.sessions.MaxDuration)
+	defer cancelMax()
+
+	var lastActive atomic.Int64
+	touch := func() { lastActive.Store(time.Now().UnixNano()) }
+	touch()
+	go watchIdle(ctx, e.sessions.IdleTimeout, &lastActive, cancelIdle)
+
+	response, err := e.runSession(ctx, lease.ContainerID, lang, req, activityReader{input, touch}, activityWriter{output, touch})
+	e.returnLease(lease, lang, response, err)
+	if err != nil {
+		e.logger.Printf("Session failed in container %s: %v", lease.ContainerID[:12], err)
+		return models.ExecuteResponse{}, fmt.Errorf("session failed: %w", err)
+	}
+
+	response.Timings.WaitMs = waited.Milliseconds()
+	response.Timings.TotalMs = time.Since(start).Milliseconds()
+
+	e.logger.Printf("Session finished in container %s with status %s (exit code %d)", lease.ContainerID[:12], response.Status, response.ExitCode)
+	return response, nil
+}
+
+func (e *Executor) runSession(ctx context.Context, containerID string, lang *language, req models.SessionRequest, input io.Reader, output io.Writer) (models.ExecuteResponse, error) {
+	var timings models.Timings
+
+	files, fileName, err := e.bundle(lang, sessionExecuteRequest(req))
+	if err != nil {
+		return models.ExecuteResponse{}, err
+	}
+	if len(files) > 0 {
+		if err := e.copyFilesToContainer(ctx, containerID, files); err != nil {
+			return models.ExecuteResponse{}, fmt.Errorf("failed to copy code to container: %w", err)
+		}
+	}
+
+	cmd := lang.command(lang.Repl, "")
+	if fileName != "" {
+		if len(lang.Compile) > 0 {
+			started := time.Now()
+			status, result, err := e.compile(ctx, containerID, lang, fileName, files, output)
+			timings.CompileMs = time.Since(started).Milliseconds()
+			status, err = sessionStatus(ctx, status, err)
+			if err != nil {
+				return models.ExecuteResponse{}, err
+			}
+			if status != models.StatusOK {
+				return models.ExecuteResponse{ExitCode: result.ExitCode, Status: status, Timings: timings}, nil
+			}
+		}
+
+		cmd = lang.command(lang.Run, fileName)
+	}
+
+	started := time.Now()
+	result, err := e.runtime.Exec(ctx, containerID, ExecOptions{Cmd: cmd, WorkDir: workDir, Stdin: input, Stdout: output, Tty: true})
+	timings.RunMs = time.Since(started).Milliseconds()
+	status, err := executionStatus(ctx, result, err)
+	status, err = sessionStatus(ctx, status, err)
+	if err != nil {
+		return models.ExecuteResponse{}, fmt.Errorf("failed to run session in container: %w", err)
+	}
+
+	return models.ExecuteResponse{ExitCode: result.ExitCode, Status: status, Timings: timings}, nil
+}
+
+func sessionExecuteRequest(req models.SessionRequest) models.ExecuteRequest {
+	return models.ExecuteRequest{
+		Language:   req.Language,
+		Code:       req.Code,
+		Files:      req.Files,
+		Entrypoint: req.Entrypoint,
+	}
+}
+
+// sessionStatus reports sessions ended by the idle timeout as timed out.
+func sessionStatus(ctx context.Context, status models.ExecutionStatus, err error) (models.ExecutionStatus, error) {
+	if errors.Is(context.Cause(ctx), errSessionIdle) {
+		return models.StatusTimeout, nil
+	}
+	return status, err
+}
+
+// watchIdle cancels ctx with errSessionIdle once lastActive is older than timeout.
+func watchIdle(ctx context.Context, timeout time.Duration, lastActive *atomic.Int64, cancel context.CancelCauseFunc) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		remaining := timeout - time.Since(time.Unix(0, lastActive.Load()))
+		if remaining <= 0 {
+			cancel(errSessionIdle)
+			return
+		}
+		timer.Reset(remaining)
+	}
+}
+
+// activityReader and activityWriter call touch on every read and write.
+type activityReader struct {
+	r     io.Reader
+	touch func()
+}
+
+func (a activityReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if n > 0 {
+		a.touch()
+	}
+	return n, err
+}
+
+type activityWriter struct {
+	w     io.Writer
+	touch func()
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+	a.touch()
+	return a.w.Write(p)
+}
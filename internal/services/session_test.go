@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// sessionLanguages are the test languages with a REPL for python3.
+func sessionLanguages() map[string]models.Language {
+	python := testLanguages["python3"]
+	python.Repl = []string{"python3"}
+	return map[string]models.Language{"python3": python, "cpp": testLanguages["cpp"]}
+}
+
+// terminal echoes the input of a command run on a terminal.
+func terminal(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+	if !opts.Tty || opts.Stderr != nil {
+		return ExecResult{ExitCode: -1}, errors.New("not run on a terminal")
+	}
+	io.Copy(opts.Stdout, opts.Stdin)
+	return ExecResult{}, nil
+}
+
+func TestRunSession(t *testing.T) {
+	rt := newTestRuntime(terminal)
+	e := newTestExecutor(t, rt, ExecutorConfig{Languages: sessionLanguages()})
+
+	var output strings.Builder
+	response, err := e.RunSession(context.Background(), models.SessionRequest{Language: "python3"}, strings.NewReader("1 + 1\r"), &output)
+	if err != nil {
+		t.Fatalf("RunSession: %v", err)
+	}
+	if response.Status != models.StatusOK || output.String() != "1 + 1\r" {
+		t.Errorf("got status %s and output %q, want the REPL's terminal output", response.Status, output.String())
+	}
+
+	if _, err := e.RunSession(context.Background(), models.SessionRequest{Language: "cpp"}, strings.NewReader(""), io.Discard); !errors.Is(err, ErrNoRepl) {
+		t.Errorf("session of a language without REPL: got %v, want ErrNoRepl", err)
+	}
+}
+
+func TestRunSessionRejectsRuntimesWithoutTTY(t *testing.T) {
+	rt := newTestRuntime(terminal)
+	rt.NoTTY = true
+	e := newTestExecutor(t, rt, ExecutorConfig{Languages: sessionLanguages()})
+	containers := rt.Containers()
+
+	if _, err := e.RunSession(context.Background(), models.SessionRequest{Language: "python3"}, strings.NewReader(""), io.Discard); !errors.Is(err, ErrTTYUnsupported) {
+		t.Fatalf("got error %v, want ErrTTYUnsupported", err)
+	}
+	if got := rt.Containers(); !slices.Equal(got, containers) {
+		t.Errorf("got containers %v after the rejected session, want %v kept", got, containers)
+	}
+	for _, id := range containers {
+		if execs := rt.Container(id).Execs; len(execs) != 0 {
+			t.Errorf("container ran %v for a rejected session", execs)
+		}
+	}
+}
+
+func TestRunSessionLimit(t *testing.T) {
+	started := make(chan struct{})
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Tty {
+			started <- struct{}{}
+			return terminal(c, opts)
+		}
+		return ExecResult{}, nil
+	})
+	// Half of the two containers per language may be used by sessions
+	e := newTestExecutor(t, rt, ExecutorConfig{Languages: sessionLanguages(), MaxConcurrent: 2})
+
+	input, keys := io.Pipe()
+	done := make(chan error)
+	go func() {
+		_, err := e.RunSession(context.Background(), models.SessionRequest{Language: "python3"}, input, io.Discard)
+		done <- err
+	}()
+	<-started
+
+	if _, err := e.RunSession(context.Background(), models.SessionRequest{Language: "python3"}, strings.NewReader(""), io.Discard); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("session over the limit: got %v, want ErrTooManySessions", err)
+	}
+	if _, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"}); err != nil {
+		t.Errorf("Execute while a session runs: %v", err)
+	}
+
+	keys.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("RunSession: %v", err)
+	}
+	go func() { <-started }()
+	if _, err := e.RunSession(context.Background(), models.SessionRequest{Language: "python3"}, strings.NewReader(""), io.Discard); err != nil {
+		t.Errorf("session after the last one ended: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// usageTimeout bounds reading the usage counters after a run, which may
+// happen after the run's deadline.
+const usageTimeout = 5 * time.Second
+
+// usageScript prints the CPU time in microseconds, the memory high-water
+// mark in bytes and the number of OOM kills of the sandbox's cgroup, from
+// cgroup v2 or else v1. Counters the kernel does not provide print as 0.
+// Called with "reset", it first resets the high-water mark where that holds
+// for later reads too, and prints a fourth field, 1 if it did. Only cgroup
+// v1 can: v2 resets memory.peak just for the file descriptor written to,
+// which does not outlive the script, and cgroupfs may be read-only anyway.
+const usageScript = `cd /sys/fs/cgroup 2>/dev/null
+reset=0
+if [ -f cpu.stat ]; then
+	cpu=$(sed -n 's/^usage_usec //p' cpu.stat)
+	peak=$(cat memory.peak 2>/dev/null)
+	oom=$(sed -n 's/^oom_kill //p' memory.events 2>/dev/null)
+else
+	if [ "$1" = reset ] && echo 0 2>/dev/null >memory/memory.max_usage_in_bytes; then
+		reset=1
+	fi
+	cpu=$(( $(cat cpuacct/cpuacct.usage 2>/dev/null || echo 0) / 1000 ))
+	peak=$(cat memory/memory.max_usage_in_bytes 2>/dev/null)
+	oom=$(sed -n 's/^oom_kill //p' memory/memory.oom_control 2>/dev/null)
+fi
+echo "${cpu:-0} ${peak:-0} ${oom:-0} $reset"`
+
+// cgroupCounters are the cumulative counters of a sandbox's cgroup.
+type cgroupCounters struct {
+	cpuUsec   int64
+	peakBytes int64
+	oomKills  int64
+	// peakReset is set when the high-water mark was reset before reading.
+	peakReset bool
+}
+
+// readCounters reads the cgroup counters of a container, after resetting
+// the memory high-water mark if reset is set and the kernel lets it.
+func (e *Executor) readCounters(ctx context.Context, containerID string, reset bool) (cgroupCounters, error) {
+	cmd := []string{"sh", "-c", usageScript, "usage"}
+	if reset {
+		cmd = append(cmd, "reset")
+	}
+
+	var stdout, stderr bytes.Buffer
+	result, err := e.runtime.Exec(ctx, containerID, ExecOptions{
+		Cmd:    cmd,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return cgroupCounters{}, err
+	}
+	if result.ExitCode != 0 {
+		return cgroupCounters{}, fmt.Errorf("script exited with %d: %s", result.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	var c cgroupCounters
+	if _, err := fmt.Sscan(stdout.String(), &c.cpuUsec, &c.peakBytes, &c.oomKills, &c.peakReset); err != nil {
+		return cgroupCounters{}, fmt.Errorf("unexpected usage %q: %w", stdout.String(), err)
+	}
+	return c, nil
+}
+
+// measure runs fn, which runs code in the container, and returns the
+// resources it used. Reading the counters takes execs of its own, which
+// must not count against the run's time limit, so fn starts the limit
+// itself. The kernel keeps a single memory high-water mark for the sandbox:
+// unless it could be reset before the run, the peak is only known when the
+// run raised it, otherwise it may belong to an earlier job and is reported
+// as unknown. Without counters only the wall time is measured.
+func (e *Executor) measure(ctx context.Context, containerID string, fn func()) models.Usage {
+	read := func(reset bool) (cgroupCounters, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), usageTimeout)
+		defer cancel()
+		return e.readCounters(ctx, containerID, reset)
+	}
+
+	before, beforeErr := read(true)
+	started := time.Now()
+	fn()
+	usage := models.Usage{WallMs: time.Since(started).Milliseconds(), PeakMemoryKB: models.UnknownMemory}
+	if beforeErr != nil {
+		e.logger.Printf("Failed to read resource usage in container %s: %v", containerID[:12], beforeErr)
+		return usage
+	}
+
+	after, err := read(false)
+	if err != nil {
+		e.logger.Printf("Failed to read resource usage in container %s: %v", containerID[:12], err)
+		return usage
+	}
+
+	usage.CPUMs = (after.cpuUsec - before.cpuUsec) / 1000
+	if before.peakReset || after.peakBytes > before.peakBytes {
+		usage.PeakMemoryKB = after.peakBytes / 1024
+	}
+	usage.OOMKilled = after.oomKills > before.oomKills
+	return usage
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+// cgroup is the cgroup of a sandbox, answering the usage script like cgroup
+// v1, which lets the memory high-water mark be reset, or v2.
+type cgroup struct {
+	v1        bool
+	cpuUsec   int64
+	peakBytes int64
+	oomKills  int64
+}
+
+// newUsageRuntime returns a test runtime whose sandboxes share cg and whose
+// programs call run with it.
+func newUsageRuntime(cg *cgroup, run func(cg *cgroup)) *FakeRuntime {
+	rt := newTestRuntime(func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		run(cg)
+		return ExecResult{}, nil
+	})
+	next := rt.ExecFunc
+	rt.ExecFunc = func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] != "sh" || opts.Cmd[2] != usageScript {
+			return next(c, opts)
+		}
+		reset := 0
+		if cg.v1 && slices.Contains(opts.Cmd, "reset") {
+			cg.peakBytes, reset = 0, 1
+		}
+		fmt.Fprintf(opts.Stdout, "%d %d %d %d\n", cg.cpuUsec, cg.peakBytes, cg.oomKills, reset)
+		return ExecResult{}, nil
+	}
+	return rt
+}
+
+func TestExecuteMeasuresUsage(t *testing.T) {
+	tests := []struct {
+		name  string
+		cg    cgroup
+		run   func(cg *cgroup)
+		usage models.Usage
+	}{
+		{
+			name:  "v2 peak raised",
+			cg:    cgroup{cpuUsec: 5000, peakBytes: 4096 * 1024},
+			run:   func(cg *cgroup) { cg.cpuUsec += 21000; cg.peakBytes = 9120 * 1024 },
+			usage: models.Usage{CPUMs: 21, PeakMemoryKB: 9120},
+		},
+		{
+			name:  "v2 peak of an earlier job",
+			cg:    cgroup{peakBytes: 9120 * 1024},
+			run:   func(cg *cgroup) { cg.cpuUsec += 3000 },
+			usage: models.Usage{CPUMs: 3, PeakMemoryKB: models.UnknownMemory},
+		},
+		{
+			name:  "v1 peak reset",
+			cg:    cgroup{v1: true, peakBytes: 9120 * 1024},
+			run:   func(cg *cgroup) { cg.peakBytes = 2048 * 1024 },
+			usage: models.Usage{PeakMemoryKB: 2048},
+		},
+		{
+			name:  "oom kill",
+			cg:    cgroup{oomKills: 2},
+			run:   func(cg *cgroup) { cg.peakBytes = 64 << 20; cg.oomKills++ },
+			usage: models.Usage{PeakMemoryKB: 64 << 10, OOMKilled: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestExecutor(t, newUsageRuntime(&test.cg, test.run), ExecutorConfig{})
+
+			response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			response.Usage.WallMs = 0
+			if response.Usage != test.usage {
+				t.Errorf("got usage %+v, want %+v", response.Usage, test.usage)
+			}
+		})
+	}
+}
+
+func TestExecuteUnreadableUsage(t *testing.T) {
+	rt := newTestRuntime(echo)
+	next := rt.ExecFunc
+	rt.ExecFunc = func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "sh" && opts.Cmd[2] == usageScript {
+			return ExecResult{ExitCode: 1}, nil
+		}
+		return next(c, opts)
+	}
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Status != models.StatusOK || response.Usage.PeakMemoryKB != models.UnknownMemory {
+		t.Errorf("got status %s and peak %d, want a successful run with an unknown peak", response.Status, response.Usage.PeakMemoryKB)
+	}
+}
+
+func TestExecuteTimeLimitLeavesOutMeasuring(t *testing.T) {
+	rt := newTestRuntime(echo)
+	next := rt.ExecFunc
+	rt.ExecFunc = func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		if opts.Cmd[0] == "sh" && opts.Cmd[2] == usageScript {
+			time.Sleep(150 * time.Millisecond)
+		}
+		return next(c, opts)
+	}
+	e := newTestExecutor(t, rt, ExecutorConfig{Timeout: 200 * time.Millisecond})
+
+	response, err := e.Execute(context.Background(), models.ExecuteRequest{Language: "python3", Code: "print(1)"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if response.Status != models.StatusOK {
+		t.Errorf("got status %s, want %s", response.Status, models.StatusOK)
+	}
+}
+
+func TestJudgeOutOfMemory(t *testing.T) {
+	cg := &cgroup{}
+	rt := newUsageRuntime(cg, func(cg *cgroup) { cg.oomKills++ })
+	next := rt.ExecFunc
+	rt.ExecFunc = func(c *FakeContainer, opts ExecOptions) (ExecResult, error) {
+		result, err := next(c, opts)
+		if opts.Cmd[0] != "sh" {
+			result.ExitCode = exitCodeSIGKILL
+		}
+		return result, err
+	}
+	e := newTestExecutor(t, rt, ExecutorConfig{})
+
+	response, err := e.Judge(context.Background(), models.JudgeRequest{
+		Language:  "python3",
+		Code:      "x = [0] * 10**10",
+		TestCases: []models.TestCase{{Expected: ""}},
+	})
+	if err != nil {
+		t.Fatalf("Judge: %v", err)
+	}
+	if response.Verdict != models.VerdictMemoryLimitExceeded {
+		t.Errorf("got verdict %s, want %s", response.Verdict, models.VerdictMemoryLimitExceeded)
+	}
+}
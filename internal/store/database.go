@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Database is a Store backed by an SQL database. Queries are shared between
+// PostgreSQL and SQLite, only the schemas differ. SQLite numbers $N
+// parameters in the order they first appear, so queries use them in order.
+type Database struct {
+	db *sql.DB
+}
+
+// open connects to a database and creates the tables that do not exist yet.
+func open(ctx context.Context, driver, dsn, schema string) (*Database, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+	return &Database{db: db}, nil
+}
+
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// inTx runs fn in a transaction that is committed if fn succeeds.
+func (d *Database) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
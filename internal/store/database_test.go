@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func openTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	db, err := OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestProblems(t *testing.T) {
+	db := openTestDatabase(t)
+	ctx := context.Background()
+
+	problem := models.Problem{
+		ID:          "two-sum",
+		Title:       "Two Sum",
+		Statement:   "Add *two* numbers.",
+		TimeLimitMs: 1000,
+		Languages:   []string{"python3"},
+		SampleTests: []models.TestCase{{Input: "1 2", Expected: "3"}},
+		HiddenTests: []models.TestCase{{Input: "3 4", Expected: "7"}, {Input: "5 6", Expected: "11"}},
+		Comparison:  models.Comparison{TrimWhitespace: true},
+		Checker:     &models.Checker{Language: "cpp", Code: "int main() {}"},
+	}
+	if _, err := db.CreateProblem(ctx, problem); err != nil {
+		t.Fatalf("CreateProblem: %v", err)
+	}
+	if _, err := db.CreateProblem(ctx, problem); !errors.Is(err, ErrExists) {
+		t.Errorf("got error %v creating the problem again, want ErrExists", err)
+	}
+
+	got, err := db.GetProblem(ctx, "two-sum")
+	if err != nil {
+		t.Fatalf("GetProblem: %v", err)
+	}
+	if got.Title != problem.Title || got.Statement != problem.Statement || got.TimeLimitMs != 1000 ||
+		len(got.Languages) != 1 || !got.Comparison.TrimWhitespace || got.Checker == nil || got.Checker.Language != "cpp" {
+		t.Errorf("got problem %+v, want %+v", got, problem)
+	}
+	if len(got.SampleTests) != 1 || len(got.HiddenTests) != 2 || got.HiddenTests[1].Expected != "11" {
+		t.Errorf("got sample tests %v and hidden tests %v, want them in order", got.SampleTests, got.HiddenTests)
+	}
+
+	problem.Title = "2Sum"
+	problem.HiddenTests = nil
+	problem.Checker = nil
+	if _, err := db.UpdateProblem(ctx, problem); err != nil {
+		t.Fatalf("UpdateProblem: %v", err)
+	}
+	problems, err := db.ListProblems(ctx)
+	if err != nil {
+		t.Fatalf("ListProblems: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Title != "2Sum" || problems[0].Checker != nil {
+		t.Errorf("got problems %+v, want the updated problem", problems)
+	}
+	if got, err := db.GetProblem(ctx, "two-sum"); err != nil || len(got.HiddenTests) != 0 {
+		t.Errorf("got hidden tests %v (%v) after removing them", got.HiddenTests, err)
+	}
+
+	if _, err := db.UpdateProblem(ctx, models.Problem{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v updating a missing problem, want ErrNotFound", err)
+	}
+	if err := db.DeleteProblem(ctx, "two-sum"); err != nil {
+		t.Fatalf("DeleteProblem: %v", err)
+	}
+	if err := db.DeleteProblem(ctx, "two-sum"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v deleting the problem again, want ErrNotFound", err)
+	}
+	if _, err := db.GetProblem(ctx, "two-sum"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v getting a deleted problem, want ErrNotFound", err)
+	}
+}
+
+func TestSubmissions(t *testing.T) {
+	db := openTestDatabase(t)
+	ctx := context.Background()
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	submissions := []models.Submission{
+		{User: "ada", Language: "python3", Code: "print(1)", Status: models.StatusOK, Stdout: "1\n"},
+		{User: "bob", Language: "go", Code: "package main", Status: models.StatusCompileError},
+		{User: "ada", Language: "python3", ProblemID: "two-sum", Verdict: models.VerdictAccepted},
+		{User: "ada", Language: "python3", ProblemID: "two-sum", Verdict: models.VerdictWrongAnswer, Truncated: true},
+	}
+	for i := range submissions {
+		submissions[i].CreatedAt = created
+		submission, err := db.CreateSubmission(ctx, submissions[i])
+		if err != nil {
+			t.Fatalf("CreateSubmission: %v", err)
+		}
+		if submission.ID == 0 {
+			t.Fatal("CreateSubmission returned no ID")
+		}
+		submissions[i] = submission
+	}
+
+	ids := func(filter SubmissionFilter) []int64 {
+		t.Helper()
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+		list, err := db.ListSubmissions(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListSubmissions: %v", err)
+		}
+		ids := []int64{}
+		for _, s := range list {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+	id := func(i int) int64 { return submissions[i].ID }
+
+	tests := []struct {
+		name   string
+		filter SubmissionFilter
+		want   []int64
+	}{
+		{"all", SubmissionFilter{}, []int64{id(3), id(2), id(1), id(0)}},
+		{"user", SubmissionFilter{User: "ada"}, []int64{id(3), id(2), id(0)}},
+		{"language", SubmissionFilter{Language: "go"}, []int64{id(1)}},
+		{"problem and verdict", SubmissionFilter{ProblemID: "two-sum", Verdict: models.VerdictAccepted}, []int64{id(2)}},
+		{"status", SubmissionFilter{Status: models.StatusOK}, []int64{id(0)}},
+		{"first page", SubmissionFilter{User: "ada", Limit: 2}, []int64{id(3), id(2)}},
+		{"next page", SubmissionFilter{User: "ada", Limit: 2, Before: id(2)}, []int64{id(0)}},
+		{"no match", SubmissionFilter{User: "eve"}, []int64{}},
+	}
+	for _, test := range tests {
+		if got := ids(test.filter); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got submissions %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	list, err := db.ListSubmissions(ctx, SubmissionFilter{Before: id(1), Limit: 1})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("got %d submissions (%v), want 1", len(list), err)
+	}
+	if got := list[0]; got.Stdout != "1\n" || got.Status != models.StatusOK || !got.CreatedAt.Equal(created) {
+		t.Errorf("got submission %+v, want %+v", got, submissions[0])
+	}
+}
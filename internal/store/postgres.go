@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS problems (
+	id              TEXT PRIMARY KEY,
+	title           TEXT NOT NULL,
+	statement       TEXT NOT NULL,
+	time_limit_ms   INTEGER NOT NULL,
+	memory_limit_mb INTEGER NOT NULL,
+	languages       JSONB NOT NULL,
+	comparison      JSONB NOT NULL,
+	checker         JSONB,
+	created_at      TIMESTAMPTZ NOT NULL,
+	updated_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS problem_tests (
+	problem_id TEXT NOT NULL REFERENCES problems (id) ON DELETE CASCADE,
+	sample     BOOLEAN NOT NULL,
+	position   INTEGER NOT NULL,
+	input      TEXT NOT NULL,
+	expected   TEXT NOT NULL,
+	PRIMARY KEY (problem_id, sample, position)
+);
+
+CREATE TABLE IF NOT EXISTS submissions (
+	id           BIGSERIAL PRIMARY KEY,
+	user_id      TEXT NOT NULL,
+	language     TEXT NOT NULL,
+	problem_id   TEXT NOT NULL,
+	code_hash    TEXT NOT NULL,
+	code         TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	verdict      TEXT NOT NULL,
+	exit_code    INTEGER NOT NULL,
+	stdout       TEXT NOT NULL,
+	stderr       TEXT NOT NULL,
+	truncated    BOOLEAN NOT NULL,
+	wait_ms      BIGINT NOT NULL,
+	compile_ms   BIGINT NOT NULL,
+	run_ms       BIGINT NOT NULL,
+	total_ms     BIGINT NOT NULL,
+	container_id TEXT NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS submissions_user_id ON submissions (user_id, id);
+CREATE INDEX IF NOT EXISTS submissions_problem_id ON submissions (problem_id, id);
+`
+
+// OpenPostgres connects to a PostgreSQL database and creates the tables
+// that do not exist yet.
+func OpenPostgres(ctx context.Context, dsn string) (*Database, error) {
+	return open(ctx, "pgx", dsn, postgresSchema)
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func (d *Database) ListProblems(ctx context.Context) ([]models.Problem, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, title, statement, time_limit_ms, memory_limit_mb, languages, comparison, checker, created_at, updated_at
+		FROM problems ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	problems := []models.Problem{}
+	for rows.Next() {
+		problem, err := scanProblem(rows)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, problem)
+	}
+	return problems, rows.Err()
+}
+
+func (d *Database) GetProblem(ctx context.Context, id string) (models.Problem, error) {
+	problem, err := scanProblem(d.db.QueryRowContext(ctx, `
+		SELECT id, title, statement, time_limit_ms, memory_limit_mb, languages, comparison, checker, created_at, updated_at
+		FROM problems WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Problem{}, fmt.Errorf("problem %s %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return models.Problem{}, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT sample, input, expected FROM problem_tests
+		WHERE problem_id = $1 ORDER BY sample DESC, position`, id)
+	if err != nil {
+		return models.Problem{}, err
+	}
+	defer rows.Close()
+
+	problem.SampleTests, problem.HiddenTests = []models.TestCase{}, []models.TestCase{}
+	for rows.Next() {
+		var sample bool
+		var test models.TestCase
+		if err := rows.Scan(&sample, &test.Input, &test.Expected); err != nil {
+			return models.Problem{}, err
+		}
+		if sample {
+			problem.SampleTests = append(problem.SampleTests, test)
+		} else {
+			problem.HiddenTests = append(problem.HiddenTests, test)
+		}
+	}
+	return problem, rows.Err()
+}
+
+func (d *Database) CreateProblem(ctx context.Context, problem models.Problem) (models.Problem, error) {
+	languages, comparison, checker, err := problemColumns(problem)
+	if err != nil {
+		return models.Problem{}, err
+	}
+	problem.CreatedAt = time.Now().UTC()
+	problem.UpdatedAt = problem.CreatedAt
+
+	err = d.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO problems (id, title, statement, time_limit_ms, memory_limit_mb, languages, comparison, checker, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (id) DO NOTHING`,
+			problem.ID, problem.Title, problem.Statement, problem.TimeLimitMs, problem.MemoryLimitMB,
+			languages, comparison, checker, problem.CreatedAt, problem.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("problem %s %w", problem.ID, ErrExists)
+		}
+		return insertTests(ctx, tx, problem)
+	})
+	return problem, err
+}
+
+func (d *Database) UpdateProblem(ctx context.Context, problem models.Problem) (models.Problem, error) {
+	languages, comparison, checker, err := problemColumns(problem)
+	if err != nil {
+		return models.Problem{}, err
+	}
+	problem.UpdatedAt = time.Now().UTC()
+
+	err = d.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE problems SET title = $1, statement = $2, time_limit_ms = $3, memory_limit_mb = $4,
+				languages = $5, comparison = $6, checker = $7, updated_at = $8
+			WHERE id = $9 RETURNING created_at`,
+			problem.Title, problem.Statement, problem.TimeLimitMs, problem.MemoryLimitMB,
+			languages, comparison, checker, problem.UpdatedAt, problem.ID).Scan(&problem.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("problem %s %w", problem.ID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM problem_tests WHERE problem_id = $1`, problem.ID); err != nil {
+			return err
+		}
+		return insertTests(ctx, tx, problem)
+	})
+	return problem, err
+}
+
+func (d *Database) DeleteProblem(ctx context.Context, id string) error {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM problems WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("problem %s %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func insertTests(ctx context.Context, tx *sql.Tx, problem models.Problem) error {
+	for sample, tests := range map[bool][]models.TestCase{true: problem.SampleTests, false: problem.HiddenTests} {
+		for i, test := range tests {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO problem_tests (problem_id, sample, position, input, expected)
+				VALUES ($1, $2, $3, $4, $5)`,
+				problem.ID, sample, i, test.Input, test.Expected); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// problemColumns encodes the fields of a problem stored as JSON.
+func problemColumns(problem models.Problem) (languages, comparison string, checker *string, err error) {
+	if problem.Languages == nil {
+		problem.Languages = []string{}
+	}
+	b, err := json.Marshal(problem.Languages)
+	if err != nil {
+		return "", "", nil, err
+	}
+	languages = string(b)
+
+	if b, err = json.Marshal(problem.Comparison); err != nil {
+		return "", "", nil, err
+	}
+	comparison = string(b)
+
+	if problem.Checker != nil {
+		if b, err = json.Marshal(problem.Checker); err != nil {
+			return "", "", nil, err
+		}
+		s := string(b)
+		checker = &s
+	}
+	return languages, comparison, checker, nil
+}
+
+// scanProblem reads a problem without its tests.
+func scanProblem(row interface{ Scan(dest ...any) error }) (models.Problem, error) {
+	var problem models.Problem
+	var languages, comparison, checker []byte
+	if err := row.Scan(&problem.ID, &problem.Title, &problem.Statement, &problem.TimeLimitMs, &problem.MemoryLimitMB,
+		&languages, &comparison, &checker, &problem.CreatedAt, &problem.UpdatedAt); err != nil {
+		return models.Problem{}, err
+	}
+
+	if err := json.Unmarshal(languages, &problem.Languages); err != nil {
+		return models.Problem{}, fmt.Errorf("invalid languages of problem %s: %w", problem.ID, err)
+	}
+	if err := json.Unmarshal(comparison, &problem.Comparison); err != nil {
+		return models.Problem{}, fmt.Errorf("invalid comparison of problem %s: %w", problem.ID, err)
+	}
+	if checker != nil {
+		problem.Checker = &models.Checker{}
+		if err := json.Unmarshal(checker, problem.Checker); err != nil {
+			return models.Problem{}, fmt.Errorf("invalid checker of problem %s: %w", problem.ID, err)
+		}
+	}
+	return problem, nil
+}
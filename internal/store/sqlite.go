@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"net/url"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema mirrors postgresSchema. JSON is stored as text, and
+// TIMESTAMP columns make the driver return time.Time.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS problems (
+	id              TEXT PRIMARY KEY,
+	title           TEXT NOT NULL,
+	statement       TEXT NOT NULL,
+	time_limit_ms   INTEGER NOT NULL,
+	memory_limit_mb INTEGER NOT NULL,
+	languages       TEXT NOT NULL,
+	comparison      TEXT NOT NULL,
+	checker         TEXT,
+	created_at      TIMESTAMP NOT NULL,
+	updated_at      TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS problem_tests (
+	problem_id TEXT NOT NULL REFERENCES problems (id) ON DELETE CASCADE,
+	sample     BOOLEAN NOT NULL,
+	position   INTEGER NOT NULL,
+	input      TEXT NOT NULL,
+	expected   TEXT NOT NULL,
+	PRIMARY KEY (problem_id, sample, position)
+);
+
+CREATE TABLE IF NOT EXISTS submissions (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id      TEXT NOT NULL,
+	language     TEXT NOT NULL,
+	problem_id   TEXT NOT NULL,
+	code_hash    TEXT NOT NULL,
+	code         TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	verdict      TEXT NOT NULL,
+	exit_code    INTEGER NOT NULL,
+	stdout       TEXT NOT NULL,
+	stderr       TEXT NOT NULL,
+	truncated    BOOLEAN NOT NULL,
+	wait_ms      INTEGER NOT NULL,
+	compile_ms   INTEGER NOT NULL,
+	run_ms       INTEGER NOT NULL,
+	total_ms     INTEGER NOT NULL,
+	container_id TEXT NOT NULL,
+	created_at   TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS submissions_user_id ON submissions (user_id, id);
+CREATE INDEX IF NOT EXISTS submissions_problem_id ON submissions (problem_id, id);
+`
+
+// OpenSQLite opens an SQLite database file, creating it and the tables
+// that do not exist yet.
+func OpenSQLite(ctx context.Context, path string) (*Database, error) {
+	options := url.Values{
+		"_foreign_keys": {"on"},
+		"_journal_mode": {"WAL"},
+		"_busy_timeout": {"5000"},
+	}
+	return open(ctx, "sqlite3", "file:"+path+"?"+options.Encode(), sqliteSchema)
+}
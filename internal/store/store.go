@@ -0,0 +1,52 @@
+// Package store persists the problem catalog and the submission history.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+)
+
+// Store keeps everything the server persists.
+type Store interface {
+	ProblemStore
+	SubmissionStore
+}
+
+// ProblemStore keeps the problem catalog.
+type ProblemStore interface {
+	// ListProblems returns all problems sorted by ID, without their tests.
+	ListProblems(ctx context.Context) ([]models.Problem, error)
+	GetProblem(ctx context.Context, id string) (models.Problem, error)
+	// CreateProblem fails with ErrExists if the ID is taken.
+	CreateProblem(ctx context.Context, problem models.Problem) (models.Problem, error)
+	// UpdateProblem replaces a problem, including all of its tests.
+	UpdateProblem(ctx context.Context, problem models.Problem) (models.Problem, error)
+	DeleteProblem(ctx context.Context, id string) error
+}
+
+// SubmissionStore keeps the submission history.
+type SubmissionStore interface {
+	// CreateSubmission stores a submission and returns it with its ID.
+	CreateSubmission(ctx context.Context, submission models.Submission) (models.Submission, error)
+	// ListSubmissions returns the newest submissions matching the filter.
+	ListSubmissions(ctx context.Context, filter SubmissionFilter) ([]models.Submission, error)
+}
+
+// SubmissionFilter selects submissions; empty fields match everything.
+type SubmissionFilter struct {
+	User      string
+	Language  string
+	ProblemID string
+	Status    models.ExecutionStatus
+	Verdict   models.Verdict
+	// Before only matches submissions with a lower ID, for pagination.
+	Before int64
+	Limit  int
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ikurotime/code-engine/internal/models"
+)
+
+func (d *Database) CreateSubmission(ctx context.Context, submission models.Submission) (models.Submission, error) {
+	err := d.db.QueryRowContext(ctx, `
+		INSERT INTO submissions (user_id, language, problem_id, code_hash, code, status, verdict, exit_code,
+			stdout, stderr, truncated, wait_ms, compile_ms, run_ms, total_ms, container_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id`,
+		submission.User, submission.Language, submission.ProblemID, submission.CodeHash, submission.Code,
+		string(submission.Status), string(submission.Verdict), submission.ExitCode,
+		submission.Stdout, submission.Stderr, submission.Truncated,
+		submission.Timings.WaitMs, submission.Timings.CompileMs, submission.Timings.RunMs, submission.Timings.TotalMs,
+		submission.ContainerID, submission.CreatedAt.UTC()).Scan(&submission.ID)
+	if err != nil {
+		return models.Submission{}, err
+	}
+	return submission, nil
+}
+
+func (d *Database) ListSubmissions(ctx context.Context, filter SubmissionFilter) ([]models.Submission, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.User != "" {
+		where("user_id = $%d", filter.User)
+	}
+	if filter.Language != "" {
+		where("language = $%d", filter.Language)
+	}
+	if filter.ProblemID != "" {
+		where("problem_id = $%d", filter.ProblemID)
+	}
+	if filter.Status != "" {
+		where("status = $%d", string(filter.Status))
+	}
+	if filter.Verdict != "" {
+		where("verdict = $%d", string(filter.Verdict))
+	}
+	if filter.Before > 0 {
+		where("id < $%d", filter.Before)
+	}
+
+	query := `
+		SELECT id, user_id, language, problem_id, code_hash, code, status, verdict, exit_code,
+			stdout, stderr, truncated, wait_ms, compile_ms, run_ms, total_ms, container_id, created_at
+		FROM submissions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	submissions := []models.Submission{}
+	for rows.Next() {
+		var s models.Submission
+		var status, verdict string
+		if err := rows.Scan(&s.ID, &s.User, &s.Language, &s.ProblemID, &s.CodeHash, &s.Code, &status, &verdict, &s.ExitCode,
+			&s.Stdout, &s.Stderr, &s.Truncated, &s.Timings.WaitMs, &s.Timings.CompileMs, &s.Timings.RunMs, &s.Timings.TotalMs,
+			&s.ContainerID, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Status, s.Verdict = models.ExecutionStatus(status), models.Verdict(verdict)
+		submissions = append(submissions, s)
+	}
+	return submissions, rows.Err()
+}
@@ -15,6 +15,8 @@ echo "🐳 Building sandbox containers..."
 docker build -t sandbox-python ./dockerfiles/python/
 docker build -t sandbox-nodejs ./dockerfiles/nodejs/
 docker build -t sandbox-java ./dockerfiles/java/
+docker build -t sandbox-cpp ./dockerfiles/cpp/
+docker build -t sandbox-go ./dockerfiles/go/
 
 # Install Go dependencies
 echo "📦 Installing Go dependencies..."
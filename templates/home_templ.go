@@ -8,7 +8,9 @@ package templates
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func Home(name string) templ.Component {
+import "ikurotime/code-engine/internal/models"
+
+func Home(languages []models.Language) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -29,7 +31,56 @@ func Home(name string) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"flex flex-col items-center justify-center h-screen\" x-data=\"{ lang: &#39;python3&#39;, theme: &#39;vs-dark&#39; }\"><h1 class=\"text-4xl font-bold\">CodeEngine</h1><p class=\"text-gray-500\">CodeEngine is a platform for executing code in a sandboxed environment.</p><!-- Editor Controls --><div class=\"flex gap-4 mb-4\"><!-- Language Selector --><div class=\"flex flex-col\"><label class=\"text-sm font-medium text-gray-700 mb-1\">Language:</label> <select x-model=\"lang\" @change=\"$store.editorState.setLanguage(lang)\" class=\"p-2 border border-gray-300 rounded-md\"><option value=\"python3\">Python</option> <option value=\"nodejs\">JavaScript</option></select></div><!-- Theme Selector --><div class=\"flex flex-col\"><label class=\"text-sm font-medium text-gray-700 mb-1\">Theme:</label> <select x-model=\"theme\" @change=\"$store.editorState.setTheme(theme)\" class=\"p-2 border border-gray-300 rounded-md\"><option value=\"vs\">Light</option> <option value=\"vs-dark\">Dark</option> <option value=\"hc-black\">High Contrast</option></select></div></div><form action=\"/execute\" method=\"post\" class=\"flex flex-col w-full max-w-4xl items-center justify-center\"><div id=\"container\" style=\"min-height: 400px; width: 100%;\" class=\"tailwind-ignore border border-gray-300 rounded-md mb-4\"></div><input type=\"hidden\" name=\"code\" id=\"code\"> <input type=\"hidden\" name=\"language\" x-bind:value=\"lang\"> <button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-600 text-white px-6 py-2 rounded-md transition-colors\">Execute Code</button></form><!-- Status Display --><div class=\"mt-4 text-sm text-gray-600\"><span>Language: <span x-text=\"lang\" class=\"font-medium\"></span></span> <span class=\"mx-2\">|</span> <span>Theme: <span x-text=\"theme\" class=\"font-medium\"></span></span></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"flex flex-col items-center justify-center min-h-screen py-8\" x-data=\"{ lang: &#39;python3&#39;, theme: &#39;vs-dark&#39; }\"><h1 class=\"text-4xl font-bold\">CodeEngine</h1><p class=\"text-gray-500\">CodeEngine is a platform for executing code in a sandboxed environment.</p><!-- Editor Controls --><div class=\"flex gap-4 mb-4\"><!-- Language Selector --><div class=\"flex flex-col\"><label class=\"text-sm font-medium text-gray-700 mb-1\">Language:</label> <select x-model=\"lang\" @change=\"$store.editorState.setLanguage(lang)\" class=\"p-2 border border-gray-300 rounded-md\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		for _, language := range languages {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<option value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(language.Name)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/home.templ`, Line: 18, Col: 34}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(language.Label)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/home.templ`, Line: 18, Col: 53}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(language.Version)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/home.templ`, Line: 18, Col: 74}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</option>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</select></div><!-- Theme Selector --><div class=\"flex flex-col\"><label class=\"text-sm font-medium text-gray-700 mb-1\">Theme:</label> <select x-model=\"theme\" @change=\"$store.editorState.setTheme(theme)\" class=\"p-2 border border-gray-300 rounded-md\"><option value=\"vs\">Light</option> <option value=\"vs-dark\">Dark</option> <option value=\"hc-black\">High Contrast</option></select></div></div><form action=\"/execute\" method=\"post\" x-on:submit.prevent=\"streamExecution($el)\" class=\"flex flex-col w-full max-w-4xl items-center justify-center\"><div id=\"container\" style=\"min-height: 400px; width: 100%;\" class=\"tailwind-ignore border border-gray-300 rounded-md mb-4\"></div><label for=\"stdin\" class=\"self-start text-sm font-medium text-gray-700 mb-1\">Input (stdin):</label> <textarea name=\"stdin\" id=\"stdin\" rows=\"4\" class=\"w-full p-2 border border-gray-300 rounded-md mb-4 font-mono text-sm\"></textarea> <input type=\"hidden\" name=\"code\" id=\"code\"> <input type=\"hidden\" name=\"language\" x-bind:value=\"lang\"> <button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-600 text-white px-6 py-2 rounded-md transition-colors\">Execute Code</button></form><!-- Output Panel --><div class=\"w-full max-w-4xl mt-4\"><div class=\"flex justify-between text-sm font-medium text-gray-700 mb-1\"><span>Output:</span> <span id=\"result\"></span></div><pre id=\"output\" class=\"w-full min-h-32 max-h-96 overflow-auto p-2 bg-gray-900 text-gray-100 rounded-md font-mono text-sm whitespace-pre-wrap\"></pre></div><!-- Status Display --><div class=\"mt-4 text-sm text-gray-600\"><span>Language: <span x-text=\"lang\" class=\"font-medium\"></span></span> <span class=\"mx-2\">|</span> <span>Theme: <span x-text=\"theme\" class=\"font-medium\"></span></span></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -37,7 +37,7 @@ func Layout(contents templ.Component) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</div><script type=\"module\">\n\t\timport * as monaco from 'https://cdn.jsdelivr.net/npm/monaco-editor@0.39.0/+esm';\n\n\t\t// Alpine.js global store for editor state\n\t\tAlpine.store('editorState', {\n\t\t\tlanguage: 'python',\n\t\t\ttheme: 'vs-dark',\n\t\t\tavailableLanguages: {\n\t\t\t\t'python3': 'python',\n\t\t\t\t'nodejs': 'javascript'\n\t\t\t},\n\t\t\tavailableThemes: ['vs', 'vs-dark', 'hc-black'],\n\n\t\t\t// Method to update language\n\t\t\tsetLanguage(lang) {\n\t\t\t\tthis.language = this.availableLanguages[lang] || lang;\n\t\t\t\twindow.reinitializeEditor();\n\t\t\t},\n\n\t\t\t// Method to update theme\n\t\t\tsetTheme(theme) {\n\t\t\t\tthis.theme = theme;\n\t\t\t\twindow.reinitializeEditor();\n\t\t\t}\n\t\t});\n\n\t\tlet editorInstance = null;\n\n\t\t// Function to create/recreate the Monaco editor\n\t\twindow.reinitializeEditor = function () {\n\t\t\tconst container = document.querySelector('#container');\n\t\t\tconst hiddenInput = document.querySelector('#code');\n\n\t\t\tif (!container) return; // Container might not be loaded yet\n\n\t\t\t// Preserve existing content if editor exists\n\t\t\tlet existingContent = '';\n\t\t\tif (editorInstance) {\n\t\t\t\texistingContent = editorInstance.getValue();\n\t\t\t\teditorInstance.dispose(); // Clean up the old editor\n\t\t\t}\n\n\t\t\t// Create new editor instance\n\t\t\teditorInstance = monaco.editor.create(container, {\n\t\t\t\tlanguage: Alpine.store('editorState').language,\n\t\t\t\ttheme: Alpine.store('editorState').theme,\n\t\t\t\tvalue: existingContent,\n\t\t\t\tautomaticLayout: true,\n\t\t\t\tminimap: { enabled: false },\n\t\t\t\tfontSize: 14,\n\t\t\t\tlineNumbers: 'on',\n\t\t\t\twordWrap: 'on'\n\t\t\t});\n\n\t\t\t// Update hidden input on content change\n\t\t\tfunction updateHiddenInput() {\n\t\t\t\tif (hiddenInput) {\n\t\t\t\t\thiddenInput.value = editorInstance.getValue();\n\t\t\t\t}\n\t\t\t}\n\t\t\teditorInstance.onDidChangeModelContent(updateHiddenInput);\n\n\t\t\t// Initial update of hidden input\n\t\t\tupdateHiddenInput();\n\t\t};\n\n\t\t// Initialize editor when DOM is ready\n\t\tdocument.addEventListener('DOMContentLoaded', () => {\n\t\t\t// Small delay to ensure Alpine.js is initialized\n\t\t\tsetTimeout(() => {\n\t\t\t\twindow.reinitializeEditor();\n\t\t\t}, 100);\n\t\t});\n\n\t\t// Make the editor instance globally accessible for debugging\n\t\twindow.getEditor = () => editorInstance;\n\t</script></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</div><script type=\"module\">\n\t\timport * as monaco from 'https://cdn.jsdelivr.net/npm/monaco-editor@0.39.0/+esm';\n\n\t\t// Alpine.js global store for editor state\n\t\tAlpine.store('editorState', {\n\t\t\tlanguage: 'python',\n\t\t\ttheme: 'vs-dark',\n\t\t\tavailableLanguages: {\n\t\t\t\t'python3': 'python',\n\t\t\t\t'nodejs': 'javascript',\n\t\t\t\t'java': 'java',\n\t\t\t\t'cpp': 'cpp',\n\t\t\t\t'go': 'go'\n\t\t\t},\n\t\t\tavailableThemes: ['vs', 'vs-dark', 'hc-black'],\n\n\t\t\t// Method to update language\n\t\t\tsetLanguage(lang) {\n\t\t\t\tthis.language = this.availableLanguages[lang] || lang;\n\t\t\t\twindow.reinitializeEditor();\n\t\t\t},\n\n\t\t\t// Method to update theme\n\t\t\tsetTheme(theme) {\n\t\t\t\tthis.theme = theme;\n\t\t\t\twindow.reinitializeEditor();\n\t\t\t}\n\t\t});\n\n\t\tlet editorInstance = null;\n\n\t\t// Function to create/recreate the Monaco editor\n\t\twindow.reinitializeEditor = function () {\n\t\t\tconst container = document.querySelector('#container');\n\t\t\tconst hiddenInput = document.querySelector('#code');\n\n\t\t\tif (!container) return; // Container might not be loaded yet\n\n\t\t\t// Preserve existing content if editor exists\n\t\t\tlet existingContent = '';\n\t\t\tif (editorInstance) {\n\t\t\t\texistingContent = editorInstance.getValue();\n\t\t\t\teditorInstance.dispose(); // Clean up the old editor\n\t\t\t}\n\n\t\t\t// Create new editor instance\n\t\t\teditorInstance = monaco.editor.create(container, {\n\t\t\t\tlanguage: Alpine.store('editorState').language,\n\t\t\t\ttheme: Alpine.store('editorState').theme,\n\t\t\t\tvalue: existingContent,\n\t\t\t\tautomaticLayout: true,\n\t\t\t\tminimap: { enabled: false },\n\t\t\t\tfontSize: 14,\n\t\t\t\tlineNumbers: 'on',\n\t\t\t\twordWrap: 'on'\n\t\t\t});\n\n\t\t\t// Update hidden input on content change\n\t\t\tfunction updateHiddenInput() {\n\t\t\t\tif (hiddenInput) {\n\t\t\t\t\thiddenInput.value = editorInstance.getValue();\n\t\t\t\t}\n\t\t\t}\n\t\t\teditorInstance.onDidChangeModelContent(updateHiddenInput);\n\n\t\t\t// Initial update of hidden input\n\t\t\tupdateHiddenInput();\n\t\t};\n\n\t\t// Initialize editor when DOM is ready\n\t\tdocument.addEventListener('DOMContentLoaded', () => {\n\t\t\t// Small delay to ensure Alpine.js is initialized\n\t\t\tsetTimeout(() => {\n\t\t\t\twindow.reinitializeEditor();\n\t\t\t}, 100);\n\t\t});\n\n\t\t// Run the form's code and render its output while it arrives as\n\t\t// Server-Sent Events. EventSource cannot POST, so the stream is read\n\t\t// with fetch.\n\t\twindow.streamExecution = async function (form) {\n\t\t\tconst output = document.querySelector('#output');\n\t\t\tconst result = document.querySelector('#result');\n\t\t\tconst button = form.querySelector('button[type=\"submit\"]');\n\n\t\t\tfunction append(text, className) {\n\t\t\t\tconst span = document.createElement('span');\n\t\t\t\tif (className) span.className = className;\n\t\t\t\tspan.textContent = text;\n\t\t\t\toutput.appendChild(span);\n\t\t\t\toutput.scrollTop = output.scrollHeight;\n\t\t\t}\n\n\t\t\tfunction handleEvent(event, data) {\n\t\t\t\tswitch (event) {\n\t\t\t\t\tcase 'stdout':\n\t\t\t\t\t\tappend(data);\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'stderr':\n\t\t\t\t\t\tappend(data, 'text-red-400');\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'compile':\n\t\t\t\t\t\tappend(data, 'text-yellow-300');\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'result':\n\t\t\t\t\t\tresult.textContent = data.status + ' · exit code ' + data.exitCode + ' · ' + data.timings.totalMs + ' ms';\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'error':\n\t\t\t\t\t\tresult.textContent = data.error;\n\t\t\t\t\t\tbreak;\n\t\t\t\t}\n\t\t\t}\n\n\t\t\toutput.textContent = '';\n\t\t\tresult.textContent = 'Running...';\n\t\t\tbutton.disabled = true;\n\n\t\t\ttry {\n\t\t\t\tconst response = await fetch('/execute/stream', {\n\t\t\t\t\tmethod: 'POST',\n\t\t\t\t\tbody: new URLSearchParams(new FormData(form))\n\t\t\t\t});\n\t\t\t\tif (!response.ok) {\n\t\t\t\t\tconst body = await response.json().catch(() => ({}));\n\t\t\t\t\tresult.textContent = body.error || 'Request failed (' + response.status + ')';\n\t\t\t\t\treturn;\n\t\t\t\t}\n\n\t\t\t\tconst reader = response.body.pipeThrough(new TextDecoderStream()).getReader();\n\t\t\t\tlet buffer = '';\n\t\t\t\tfor (;;) {\n\t\t\t\t\tconst { value, done } = await reader.read();\n\t\t\t\t\tif (done) break;\n\t\t\t\t\tbuffer += value;\n\n\t\t\t\t\tlet end;\n\t\t\t\t\twhile ((end = buffer.indexOf('\\n\\n')) >= 0) {\n\t\t\t\t\t\tconst block = buffer.slice(0, end);\n\t\t\t\t\t\tbuffer = buffer.slice(end + 2);\n\n\t\t\t\t\t\tlet event = 'message';\n\t\t\t\t\t\tlet data = '';\n\t\t\t\t\t\tfor (const line of block.split('\\n')) {\n\t\t\t\t\t\t\tif (line.startsWith('event: ')) event = line.slice(7);\n\t\t\t\t\t\t\telse if (line.startsWith('data: ')) data += line.slice(6);\n\t\t\t\t\t\t}\n\t\t\t\t\t\thandleEvent(event, JSON.parse(data));\n\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t} catch (err) {\n\t\t\t\tresult.textContent = 'Connection lost: ' + err.message;\n\t\t\t} finally {\n\t\t\t\tbutton.disabled = false;\n\t\t\t}\n\t\t};\n\n\t\t// Submit the form's solution to a problem and list the verdict of\n\t\t// every test.\n\t\twindow.judgeSubmission = async function (form) {\n\t\t\tconst output = document.querySelector('#output');\n\t\t\tconst result = document.querySelector('#result');\n\t\t\tconst button = form.querySelector('button[type=\"submit\"]');\n\t\t\tconst data = new FormData(form);\n\n\t\t\toutput.textContent = '';\n\t\t\tresult.textContent = 'Judging...';\n\t\t\tbutton.disabled = true;\n\n\t\t\ttry {\n\t\t\t\tconst response = await fetch(form.action, {\n\t\t\t\t\tmethod: 'POST',\n\t\t\t\t\theaders: { 'Content-Type': 'application/json' },\n\t\t\t\t\tbody: JSON.stringify({ language: data.get('language'), code: data.get('code') })\n\t\t\t\t});\n\t\t\t\tconst body = await response.json().catch(() => ({}));\n\t\t\t\tif (!response.ok) {\n\t\t\t\t\tresult.textContent = body.error || 'Request failed (' + response.status + ')';\n\t\t\t\t\treturn;\n\t\t\t\t}\n\n\t\t\t\tresult.textContent = body.verdict + ' · ' + body.passed + '/' + body.total + ' passed · ' + body.timings.totalMs + ' ms';\n\t\t\t\tif (body.compileOutput) output.textContent = body.compileOutput + '\\n';\n\t\t\t\tbody.tests.forEach((test, i) => {\n\t\t\t\t\toutput.textContent += 'Test ' + (i + 1) + ': ' + test.verdict + ' · ' + test.timeMs + ' ms\\n';\n\t\t\t\t});\n\t\t\t} catch (err) {\n\t\t\t\tresult.textContent = 'Connection lost: ' + err.message;\n\t\t\t} finally {\n\t\t\t\tbutton.disabled = false;\n\t\t\t}\n\t\t};\n\n\t\t// Make the editor instance globally accessible for debugging\n\t\twindow.getEditor = () => editorInstance;\n\t</script></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
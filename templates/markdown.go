@@ -0,0 +1,20 @@
+package templates
+
+import (
+	"context"
+	"io"
+
+	"github.com/a-h/templ"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdownRenderer leaves out raw HTML, so statements cannot inject scripts.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// markdown renders Markdown as HTML.
+func markdown(source string) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		return markdownRenderer.Convert([]byte(source), w)
+	})
+}